@@ -0,0 +1,69 @@
+package phpredishandler
+
+import (
+	"strings"
+)
+
+// DefaultRedisBindingTypes are the binding types that configure the Redis
+// session backend when BP_PHP_REDIS_SESSION_BINDING_TYPES is not set. The
+// Redis-compatible servers Valkey, KeyDB and Dragonfly are recognized out of
+// the box.
+var DefaultRedisBindingTypes = []string{
+	RedisBindingType,
+	"php-valkey-session",
+	"valkey",
+	"php-keydb-session",
+	"keydb",
+	"php-dragonfly-session",
+	"dragonfly",
+}
+
+// RedisBindingTypes returns the binding types that configure the Redis
+// session backend. BindingTypesEnv replaces the defaults with a list of types
+// separated by commas or whitespace.
+func RedisBindingTypes(environment Environment) []string {
+	value, ok := environment.Lookup(BindingTypesEnv)
+	if !ok {
+		return DefaultRedisBindingTypes
+	}
+
+	types := splitList(value)
+	if len(types) == 0 {
+		return DefaultRedisBindingTypes
+	}
+
+	return types
+}
+
+// RedisFlavor returns the Redis-compatible server a binding type refers to:
+// valkey, keydb, dragonfly or redis. All of them are configured the same way.
+func RedisFlavor(bindingType string) string {
+	for _, flavor := range []string{"valkey", "keydb", "dragonfly"} {
+		if strings.Contains(strings.ToLower(bindingType), flavor) {
+			return flavor
+		}
+	}
+
+	return "redis"
+}
+
+// isSessionBindingType reports whether a binding type is dedicated to PHP
+// sessions, such as php-redis-session, rather than a generic server type.
+func isSessionBindingType(bindingType string) bool {
+	return strings.HasPrefix(bindingType, "php-") && strings.HasSuffix(bindingType, "-session")
+}
+
+// describeBindingTypes formats binding types for log and error messages, for
+// example `a`, `b` or `c`.
+func describeBindingTypes(types []string) string {
+	quoted := make([]string, len(types))
+	for i, typ := range types {
+		quoted[i] = "`" + typ + "`"
+	}
+
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+}
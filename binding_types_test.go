@@ -0,0 +1,42 @@
+package phpredishandler_test
+
+import (
+	"testing"
+
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testBindingTypes(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	context("RedisBindingTypes", func() {
+		it("returns the defaults when BP_PHP_REDIS_SESSION_BINDING_TYPES is not set", func() {
+			Expect(phpredishandler.RedisBindingTypes(phpredishandler.Environment{})).To(Equal(phpredishandler.DefaultRedisBindingTypes))
+		})
+
+		it("returns the defaults when BP_PHP_REDIS_SESSION_BINDING_TYPES is empty", func() {
+			Expect(phpredishandler.RedisBindingTypes(phpredishandler.Environment{
+				"BP_PHP_REDIS_SESSION_BINDING_TYPES": " , ",
+			})).To(Equal(phpredishandler.DefaultRedisBindingTypes))
+		})
+
+		it("splits BP_PHP_REDIS_SESSION_BINDING_TYPES on commas and whitespace", func() {
+			Expect(phpredishandler.RedisBindingTypes(phpredishandler.Environment{
+				"BP_PHP_REDIS_SESSION_BINDING_TYPES": "valkey, php-valkey-session redis",
+			})).To(Equal([]string{"valkey", "php-valkey-session", "redis"}))
+		})
+	})
+
+	context("RedisFlavor", func() {
+		it("returns the Redis-compatible server named by the binding type", func() {
+			Expect(phpredishandler.RedisFlavor("php-redis-session")).To(Equal("redis"))
+			Expect(phpredishandler.RedisFlavor("Valkey")).To(Equal("valkey"))
+			Expect(phpredishandler.RedisFlavor("php-keydb-session")).To(Equal("keydb"))
+			Expect(phpredishandler.RedisFlavor("dragonfly")).To(Equal("dragonfly"))
+			Expect(phpredishandler.RedisFlavor("some-session-type")).To(Equal("redis"))
+		})
+	})
+}
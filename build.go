@@ -1,9 +1,14 @@
 package phpredishandler
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/fs"
 	"github.com/paketo-buildpacks/packit/v2/scribe"
 	"github.com/paketo-buildpacks/packit/v2/servicebindings"
 )
@@ -11,8 +16,12 @@ import (
 //go:generate faux --interface BuildBindingResolver --output fakes/build_binding_resolver.go
 //go:generate faux --interface ConfigParser --output fakes/config_parser.go
 //go:generate faux --interface ConfigWriter --output fakes/config_writer.go
+//go:generate faux --interface MemcachedParser --output fakes/memcached_parser.go
+//go:generate faux --interface MemcachedWriter --output fakes/memcached_writer.go
+//go:generate faux --interface ExtensionInspector --output fakes/extension_inspector.go
 
 type BuildBindingResolver interface {
+	Resolve(typ, provider, platformDir string) ([]servicebindings.Binding, error)
 	ResolveOne(typ, provider, platformDir string) (servicebindings.Binding, error)
 }
 
@@ -20,18 +29,35 @@ type ConfigParser interface {
 	Parse(dir string) (RedisConfig, error)
 }
 
+type MemcachedParser interface {
+	Parse(dir string) (MemcachedConfig, error)
+}
+
 type ConfigWriter interface {
-	Write(redisConfig RedisConfig, layerPath, cnbPath string) (string, error)
+	Write(redisConfig RedisConfig, extensions []string, layerPath, cnbPath string) (string, error)
+	WritePredisHandler(redisConfig RedisConfig, predisPath, layerPath, cnbPath string) (string, error)
+	WriteFpmPool(redisConfig RedisConfig, pool, dir, cnbPath string) (string, error)
+}
+
+type MemcachedWriter interface {
+	Write(memcachedConfig MemcachedConfig, extensions []string, layerPath, cnbPath string) (string, error)
+}
+
+type ExtensionInspector interface {
+	Inspect() (PhpInstallation, error)
+	IniFiles() ([]string, error)
+	RedisCompressions(extensions []string) ([]string, error)
+	RequiredExtensions(extension string) ([]string, error)
 }
 
 // Build will return a packit.BuildFunc that will be invoked during the build
 // phase of the buildpack lifecycle.
 //
-func Build(redisBindingConfigParser ConfigParser, bindingResolver BuildBindingResolver, redisConfigWriter ConfigWriter, logger scribe.Emitter) packit.BuildFunc {
+func Build(redisBindingConfigParser ConfigParser, memcachedBindingConfigParser MemcachedParser, bindingResolver BuildBindingResolver, redisConfigWriter ConfigWriter, memcachedConfigWriter MemcachedWriter, extensionInspector ExtensionInspector, environment Environment, logger scribe.Emitter) packit.BuildFunc {
 	return func(context packit.BuildContext) (packit.BuildResult, error) {
 		logger.Title("%s %s", context.BuildpackInfo.Name, context.BuildpackInfo.Version)
 
-		logger.Debug.Process("Getting the layer associated with the redis configuration")
+		logger.Debug.Process("Getting the layer associated with the session configuration")
 		phpRedisLayer, err := context.Layers.Get(PhpRedisLayer)
 		if err != nil {
 			return packit.BuildResult{}, err
@@ -44,39 +70,670 @@ func Build(redisBindingConfigParser ConfigParser, bindingResolver BuildBindingRe
 			return packit.BuildResult{}, err
 		}
 
-		logger.Debug.Process("Resolving the %s service binding", RedisBindingType)
-		binding, err := bindingResolver.ResolveOne(RedisBindingType, "", context.Platform.Path)
+		scope, err := LoadSessionScope(environment)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		buildSession, err := environment.Bool(BuildSessionEnv)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		bindingType, err := selectBindingType(bindingResolver, RedisBindingTypes(environment), context.Platform.Path)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
-		logger.Debug.Break()
 
-		logger.Debug.Process("Parsing the %s service binding", RedisBindingType)
-		redisConfig, err := redisBindingConfigParser.Parse(binding.Path)
+		logger.Debug.Process("Resolving the %s service binding", bindingType)
+		binding, err := bindingResolver.ResolveOne(bindingType, "", context.Platform.Path)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 		logger.Debug.Break()
 
-		// Use go templating to write the config file
-		logger.Process("Writing the redis configuration")
-		redisConfigPath, err := redisConfigWriter.Write(redisConfig, phpRedisLayer.Path, context.CNBPath)
+		var result packit.BuildResult
+		switch bindingType {
+		case MemcachedBindingType:
+			result, err = buildMemcached(context, binding, scope, phpRedisLayer, memcachedBindingConfigParser, memcachedConfigWriter, extensionInspector, logger)
+		default:
+			result, err = buildRedis(context, binding, bindingType, scope, phpRedisLayer, redisBindingConfigParser, redisConfigWriter, extensionInspector, environment, logger)
+		}
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		iniPolicy, err := checkIniConflicts(context, phpRedisLayer, extensionInspector, environment, logger)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
-		logger.Subprocess("Redis configuration written to: %s", redisConfigPath)
-		logger.Break()
 
 		phpRedisLayer.LaunchEnv.Append("PHP_INI_SCAN_DIR",
 			phpRedisLayer.Path,
 			string(os.PathListSeparator),
 		)
-		logger.EnvironmentVariables(phpRedisLayer)
 
+		// Buildpacks after this one can append their own directories to
+		// PHP_INI_SCAN_DIR, so the session-switch helper moves the
+		// directories of the layer behind them at launch.
+		if iniPolicy == IniConflictsOverride {
+			phpRedisLayer.LaunchEnv.Default(IniLoadLastEnv, "true")
+		}
+
+		// Build processes are not web processes, but the session
+		// configuration was requested for the build explicitly.
+		if buildSession {
+			buildDirs := []string{phpRedisLayer.Path}
+			if scope.WebOnly() {
+				buildDirs = append(buildDirs, filepath.Join(phpRedisLayer.Path, WebIniDir))
+			}
+
+			phpRedisLayer.BuildEnv.Append("PHP_INI_SCAN_DIR",
+				strings.Join(buildDirs, string(os.PathListSeparator)),
+				string(os.PathListSeparator),
+			)
+			phpRedisLayer.Build = true
+		}
+		// The process types of the web scope load WebIniDir, and those with
+		// their own configuration load it after that.
+		processDirs := map[string][]string{}
+		for _, process := range scope.Processes {
+			processDirs[process] = append(processDirs[process], filepath.Join(phpRedisLayer.Path, WebIniDir))
+		}
+
+		if bindingType != MemcachedBindingType {
+			for _, process := range SessionProcesses(environment) {
+				processDirs[process] = append(processDirs[process], ProcessIniPath(phpRedisLayer.Path, process))
+			}
+		}
+
+		for process, dirs := range processDirs {
+			phpRedisLayer.ProcessLaunchEnv[process] = packit.Environment{}
+			phpRedisLayer.ProcessLaunchEnv[process].Append("PHP_INI_SCAN_DIR",
+				strings.Join(dirs, string(os.PathListSeparator)),
+				string(os.PathListSeparator),
+			)
+		}
+		logged := phpRedisLayer
+		logged.LaunchEnv = redactSecrets(phpRedisLayer.LaunchEnv, environment)
+		logger.EnvironmentVariables(logged)
+
+		// The session-switch helper disables the session configuration at
+		// launch when SessionEnabledEnv is false.
+		phpRedisLayer.ExecD = []string{filepath.Join(context.CNBPath, "bin", SessionSwitchExecD)}
 		phpRedisLayer.Launch = true
 
-		return packit.BuildResult{
-			Layers: []packit.Layer{phpRedisLayer},
-		}, nil
+		result.Layers = append(result.Layers, phpRedisLayer)
+		return result, nil
+	}
+}
+
+// selectBindingType returns the type of the session binding to configure.
+// Only one session backend can be configured at a time. When bindings of more
+// than one of the accepted types are found, a single php-*-session binding is
+// preferred over generic ones such as valkey, which the application may bind
+// for its cache; otherwise they are rejected. When there is no binding at all,
+// the first Redis binding type is returned so that resolving it reports the
+// missing binding.
+func selectBindingType(bindingResolver BuildBindingResolver, redisBindingTypes []string, platformPath string) (string, error) {
+	var found []string
+	for _, typ := range append(append([]string{}, redisBindingTypes...), MemcachedBindingType) {
+		bindings, err := bindingResolver.Resolve(typ, "", platformPath)
+		if err != nil {
+			return "", err
+		}
+
+		if len(bindings) > 0 {
+			found = append(found, typ)
+		}
+	}
+
+	if len(found) > 1 {
+		var sessionTypes []string
+		for _, typ := range found {
+			if isSessionBindingType(typ) {
+				sessionTypes = append(sessionTypes, typ)
+			}
+		}
+
+		if len(sessionTypes) == 1 {
+			return sessionTypes[0], nil
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return redisBindingTypes[0], nil
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("found bindings of type %s and %s: only one session backend can be configured", strings.Join(found[:len(found)-1], ", "), found[len(found)-1])
 	}
 }
+
+// inspectPhp inspects the PHP installation and logs what was found.
+func inspectPhp(extensionInspector ExtensionInspector, logger scribe.Emitter) (PhpInstallation, error) {
+	logger.Process("Inspecting the PHP installation")
+	installation, err := extensionInspector.Inspect()
+	if err != nil {
+		return PhpInstallation{}, err
+	}
+	logger.Subprocess("PHP version: %s", installation.Version)
+	logger.Subprocess("Extension directory: %s", installation.ExtensionDir)
+
+	return installation, nil
+}
+
+// logLoadedExtensions logs which of the given extensions PHP already loads.
+func logLoadedExtensions(installation PhpInstallation, extensions []string, logger scribe.Emitter) {
+	for _, name := range extensions {
+		if installation.Loaded(name) {
+			logger.Subprocess("Extension %s is already loaded", name)
+		}
+	}
+}
+
+// redactSecrets returns a copy of the launch environment in which the
+// exported and framework variables that contain the password are redacted.
+func redactSecrets(env packit.Environment, environment Environment) packit.Environment {
+	secrets := FrameworkSecrets
+
+	// Invalid export settings were reported when the variables were
+	// exported.
+	names, err := ExportNames(environment)
+	if err == nil {
+		secrets = append(SecretExportNames(names), secrets...)
+	}
+
+	redacted := packit.Environment{}
+	for key, value := range env {
+		redacted[key] = value
+	}
+
+	for _, name := range secrets {
+		if _, ok := redacted[name+".default"]; ok {
+			redacted[name+".default"] = "REDACTED"
+		}
+	}
+
+	return redacted
+}
+
+// checkIniConflicts compares the session directives written into the layer
+// with those of the PHP installation's ini files and the application's
+// AppIniDir, and applies the IniConflictsEnv policy to the ones that differ.
+// It returns the policy. With IniConflictsOverride, the layer is loaded after
+// all other ini directories at launch, so its directives take precedence.
+//
+// The ini files are those PHP loads during the build. Directories that later
+// buildpacks only add to the launch environment are not checked.
+func checkIniConflicts(context packit.BuildContext, layer packit.Layer, extensionInspector ExtensionInspector, environment Environment, logger scribe.Emitter) (string, error) {
+	policy, err := IniConflictsPolicy(environment)
+	if err != nil {
+		return "", err
+	}
+
+	ours, err := ScanIniDirectives([]string{layer.Path, filepath.Join(layer.Path, WebIniDir)})
+	if err != nil {
+		return "", err
+	}
+
+	iniFiles, err := extensionInspector.IniFiles()
+	if err != nil {
+		return "", err
+	}
+
+	others, err := ScanIniDirectives(append(iniFiles, filepath.Join(context.WorkingDir, AppIniDir)))
+	if err != nil {
+		return "", err
+	}
+
+	conflicts := FindIniConflicts(ours, others)
+	if len(conflicts) == 0 {
+		return policy, nil
+	}
+
+	logger.Process("Checking for conflicting session settings")
+	switch policy {
+	case IniConflictsFail:
+		var descriptions []string
+		for _, conflict := range conflicts {
+			descriptions = append(descriptions, conflict.String())
+		}
+
+		return "", fmt.Errorf("found ini files that set the session settings of the %s layer to different values:\n  %s", layer.Name, strings.Join(descriptions, "\n  "))
+	case IniConflictsOverride:
+		logger.Subprocess("Loading the %s layer after the other ini files at launch", layer.Name)
+		for _, conflict := range conflicts {
+			conflict = conflict.Redacted()
+			logger.Subprocess("Using %s = %q instead of %q from %s:%d", conflict.Name, conflict.Expected, conflict.Value, conflict.File, conflict.Line)
+		}
+	default:
+		for _, conflict := range conflicts {
+			logger.Subprocess("Warning: %s", conflict)
+		}
+	}
+	logger.Break()
+
+	return policy, nil
+}
+
+// scopeExtensions returns the directory to write the session configuration
+// into and the extensions it loads. In the web scope, the extension= lines are
+// written into ExtensionsIniFile in the layer, which every process loads, and
+// the session configuration goes into WebIniDir without them.
+func scopeExtensions(scope SessionScope, layerPath string, extensions []string, logger scribe.Emitter) (string, []string, error) {
+	if !scope.WebOnly() {
+		return layerPath, extensions, nil
+	}
+
+	webDir := filepath.Join(layerPath, WebIniDir)
+	err := os.MkdirAll(webDir, os.ModePerm)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var content strings.Builder
+	for _, extension := range extensions {
+		fmt.Fprintf(&content, "extension=%s\n", extension)
+	}
+
+	err = os.WriteFile(filepath.Join(layerPath, ExtensionsIniFile), []byte(content.String()), os.ModePerm)
+	if err != nil {
+		return "", nil, err
+	}
+
+	logger.Subprocess("Loading the session configuration in the %s process types only", strings.Join(scope.Processes, ", "))
+	logger.Subprocess("Extensions written to: %s", filepath.Join(layerPath, ExtensionsIniFile))
+
+	return webDir, nil, nil
+}
+
+// writeProcessConfig writes the session configuration of a process type into
+// its ProcessIniPath, from the settings of its ProcessEnvironment on top of
+// redisConfig. The extensions are loaded by the layer, so a process type
+// cannot change the session client or the extensions it needs.
+func writeProcessConfig(context packit.BuildContext, process string, redisConfig RedisConfig, sessionClient SessionClient, predisPath, templatePath string, layer packit.Layer, writer ConfigWriter, environment Environment, logger scribe.Emitter) error {
+	processConfig, err := redisConfig.ApplyEnvironment(ProcessEnvironment(environment, process))
+	if err != nil {
+		return fmt.Errorf("failed to configure the %s process type: %w", process, err)
+	}
+
+	processClient, err := LookupSessionClient(processConfig.Client)
+	if err != nil {
+		// not tested
+		return err
+	}
+
+	if processClient.Name != sessionClient.Name || !slices.Equal(processConfig.Extensions(processClient), redisConfig.Extensions(sessionClient)) {
+		return fmt.Errorf("failed to configure the %s process type: it must use the %s session client and the same extensions as the other processes", process, sessionClient.Name)
+	}
+
+	logger.Process("Writing the redis configuration of the %s process type", process)
+	for _, warning := range processConfig.Warnings() {
+		logger.Subprocess("Warning: %s", warning)
+	}
+
+	dir := ProcessIniPath(layer.Path, process)
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	if predisPath != "" {
+		handlerPath, err := writer.WritePredisHandler(processConfig, predisPath, dir, context.CNBPath)
+		if err != nil {
+			return err
+		}
+		logger.Subprocess("Session handler written to: %s", handlerPath)
+	}
+
+	path, err := writer.Write(processConfig, nil, dir, templatePath)
+	if err != nil {
+		return err
+	}
+	logger.Subprocess("Redis configuration written to: %s", path)
+	logger.Break()
+
+	return nil
+}
+
+// buildMemcached writes php-memcached.ini from a php-memcached-session binding.
+func buildMemcached(context packit.BuildContext, binding servicebindings.Binding, scope SessionScope, layer packit.Layer, parser MemcachedParser, writer MemcachedWriter, extensionInspector ExtensionInspector, logger scribe.Emitter) (packit.BuildResult, error) {
+	logger.Debug.Process("Parsing the %s service binding", MemcachedBindingType)
+	memcachedConfig, err := parser.Parse(binding.Path)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+	logger.Debug.Break()
+
+	installation, err := inspectPhp(extensionInspector, logger)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+	logLoadedExtensions(installation, MemcachedExtensions, logger)
+	logger.Break()
+
+	extensions, err := installation.ExtensionsToLoad(MemcachedExtensions)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	logger.Process("Writing the memcached configuration")
+	configDir, extensions, err := scopeExtensions(scope, layer.Path, extensions, logger)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	configPath, err := writer.Write(memcachedConfig, extensions, configDir, context.CNBPath)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+	logger.Subprocess("Memcached configuration written to: %s", configPath)
+
+	_, err = WriteDisabledIni(layer.Path, false)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+	logger.Break()
+
+	return packit.BuildResult{}, nil
+}
+
+// buildRedis writes php-redis.ini from a binding of one of the Redis binding
+// types and installs the session tooling.
+func buildRedis(context packit.BuildContext, binding servicebindings.Binding, bindingType string, scope SessionScope, phpRedisLayer packit.Layer, redisBindingConfigParser ConfigParser, redisConfigWriter ConfigWriter, extensionInspector ExtensionInspector, environment Environment, logger scribe.Emitter) (packit.BuildResult, error) {
+	logger.Debug.Process("Parsing the %s service binding", bindingType)
+	redisConfig, err := redisBindingConfigParser.Parse(binding.Path)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+	logger.Debug.Break()
+
+	// Valkey, KeyDB and Dragonfly speak the Redis protocol, so the flavor
+	// only changes what is logged.
+	flavor := RedisFlavor(bindingType)
+	redisConfig, err = redisConfig.ApplyEnvironment(environment)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	logger.Process("Configuring the %s session backend", flavor)
+	if binding.Type != "" {
+		logger.Subprocess("Using the %s service binding", binding.Type)
+	}
+	logger.Subprocess("Session cookie: %s", redisConfig.CookieSummary())
+	for _, warning := range redisConfig.Warnings() {
+		logger.Subprocess("Warning: %s", warning)
+	}
+	logger.Break()
+
+	sessionClient, err := LookupSessionClient(redisConfig.Client)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	installation, err := inspectPhp(extensionInspector, logger)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	if (redisConfig.SidLength != 0 || redisConfig.SidBitsPerCharacter != 0) && installation.AtLeast(8, 4) {
+		logger.Subprocess("Warning: sid_length and sid_bits_per_character are deprecated as of PHP 8.4")
+	}
+
+	// Predis has no session locking or early refresh, so there is no
+	// fallback when they are enabled.
+	if redisConfig.Client == "" && !redisConfig.requiresPhpRedis() {
+		_, err = installation.ExtensionsToLoad(redisConfig.Extensions(sessionClient))
+		if err != nil {
+			// Only fall back when the application vendors Predis, so that the
+			// build reports the missing extension rather than the missing
+			// package.
+			_, predisErr := VendoredPredis(context.WorkingDir)
+			if predisErr != nil {
+				return packit.BuildResult{}, fmt.Errorf("%w, and the %s session client cannot be used instead: %s", err, SessionClientPredis, predisErr)
+			}
+
+			logger.Subprocess("Falling back to the %s session client: %s", SessionClientPredis, err)
+			redisConfig.Client = SessionClientPredis
+			sessionClient, err = LookupSessionClient(redisConfig.Client)
+			if err != nil {
+				// untested
+				return packit.BuildResult{}, err
+			}
+		}
+	}
+
+	requiredExtensions := redisConfig.Extensions(sessionClient)
+	if sessionClient.Name == SessionClientPhpRedis {
+		linked, err := phpRedisLinkedExtensions(installation, extensionInspector)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		for _, name := range linked {
+			if !slices.Contains(requiredExtensions, name) {
+				requiredExtensions = append([]string{name}, requiredExtensions...)
+			}
+		}
+	}
+
+	logger.Subprocess("Session client: %s", sessionClient.Name)
+	logLoadedExtensions(installation, requiredExtensions, logger)
+
+	logger.Break()
+
+	extensions, err := installation.ExtensionsToLoad(requiredExtensions)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	if redisConfig.Compression != "" && sessionClient.Name == SessionClientPhpRedis {
+		logger.Process("Verifying %s compression support", redisConfig.Compression)
+		compressions, err := extensionInspector.RedisCompressions(extensions)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if !slices.Contains(compressions, redisConfig.Compression) {
+			return packit.BuildResult{}, fmt.Errorf("the phpredis extension of PHP %s was built without %s compression support: it supports %q", installation.Version, redisConfig.Compression, compressions)
+		}
+		logger.Subprocess("phpredis supports %s compression", redisConfig.Compression)
+		logger.Break()
+	}
+
+	var predisPath string
+	if sessionClient.PurePHP() {
+		logger.Process("Locating Predis")
+		predisPath, err = VendoredPredis(context.WorkingDir)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+		logger.Subprocess("Using the application's Predis: %s", PredisVendorDir)
+		logger.Break()
+	}
+
+	// Use go templating to write the config file
+	logger.Process("Writing the redis configuration")
+	templatePath, err := RedisIniTemplate(context.WorkingDir, context.CNBPath)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	if templatePath == filepath.Join(context.WorkingDir, CustomRedisIniTemplate) {
+		logger.Subprocess("Using the application's template: %s", CustomRedisIniTemplate)
+	}
+
+	configDir, extensions, err := scopeExtensions(scope, phpRedisLayer.Path, extensions, logger)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	// The session handler is written next to the php-redis.ini that
+	// prepends it.
+	if predisPath != "" {
+		handlerPath, err := redisConfigWriter.WritePredisHandler(redisConfig, predisPath, configDir, context.CNBPath)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+		logger.Subprocess("Session handler written to: %s", handlerPath)
+	}
+
+	redisConfigPath, err := redisConfigWriter.Write(redisConfig, extensions, configDir, templatePath)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+	logger.Subprocess("Redis configuration written to: %s", redisConfigPath)
+
+	_, err = WriteDisabledIni(phpRedisLayer.Path, sessionClient.PurePHP())
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+	logger.Break()
+
+	for _, process := range SessionProcesses(environment) {
+		err = writeProcessConfig(context, process, redisConfig, sessionClient, predisPath, templatePath, phpRedisLayer, redisConfigWriter, environment, logger)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+	}
+
+	export, err := environment.Bool(ExportEnv)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	exported := map[string]string{}
+	if export {
+		names, err := ExportNames(environment)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		// The LaunchEnv is shared with the caller, which logs it with the
+		// secrets redacted. The variables are defaults, so that operators
+		// can point application code at a different server at launch.
+		logger.Process("Exporting the connection details for application code")
+		exported = redisConfig.ExportedEnvironment(names)
+		for _, detail := range ExportedDetails {
+			name := names[detail]
+			if value, ok := exported[name]; ok {
+				phpRedisLayer.LaunchEnv.Default(name, value)
+				logger.Subprocess("%s: %s", detail, name)
+			}
+		}
+		logger.Break()
+	}
+
+	framework, frameworkSource, err := SelectFramework(context.WorkingDir, environment)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	if framework.Name != "" {
+		logger.Process("Configuring the %s framework", framework.Title)
+		if frameworkSource != "" {
+			logger.Subprocess("Detected from %s", frameworkSource)
+		}
+
+		frameworkEnv := framework.Environment(redisConfig, sessionClient)
+		var names []string
+		for name := range frameworkEnv {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		// The variables are defaults, so that the application's own
+		// settings, which Laravel also uses for caches and queues, take
+		// precedence at launch.
+		for _, name := range names {
+			if value, ok := exported[name]; ok && value != frameworkEnv[name] {
+				return packit.BuildResult{}, fmt.Errorf("%s and the %s integration set %s to different values: rename it with %s or set %s=%s", ExportEnv, framework.Title, name, ExportNamesEnv, FrameworkEnv, FrameworkNone)
+			}
+
+			phpRedisLayer.LaunchEnv.Default(name, frameworkEnv[name])
+		}
+		logger.Subprocess("Setting %s", strings.Join(names, ", "))
+
+		if framework.Note != "" {
+			logger.Subprocess("Note: %s", framework.Note)
+		}
+		logger.Break()
+	}
+
+	fpmAdmin, err := environment.Bool(FpmAdminEnv)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	if fpmAdmin {
+		pool, _ := environment.Lookup(FpmPoolEnv)
+		if pool == "" {
+			pool = DefaultFpmPool
+		}
+
+		logger.Process("Locking the session handler in the %s php-fpm pool", pool)
+		fpmPoolPath, err := redisConfigWriter.WriteFpmPool(redisConfig, pool, phpRedisLayer.Path, context.CNBPath)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+		logger.Subprocess("php-fpm pool configuration written to: %s", fpmPoolPath)
+
+		fpmConfig, _ := environment.Lookup(FpmConfigEnv)
+		includePath, err := WriteFpmPoolInclude(context.WorkingDir, fpmPoolPath, fpmConfig)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+		logger.Subprocess("php-fpm pool include written to: %s", includePath)
+		logger.Break()
+	}
+
+	logger.Process("Installing the %s tool", SessionToolName)
+	toolPath := filepath.Join(phpRedisLayer.Path, "bin", SessionToolName)
+	err = os.MkdirAll(filepath.Dir(toolPath), os.ModePerm)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+
+	err = fs.Copy(filepath.Join(context.CNBPath, "bin", SessionToolName), toolPath)
+	if err != nil {
+		return packit.BuildResult{}, err
+	}
+	logger.Subprocess("Installed to: %s", toolPath)
+	logger.Break()
+
+	processes := []packit.Process{
+		{
+			Type:    MigrateProcessType,
+			Command: toolPath,
+			Args:    []string{"migrate"},
+			Direct:  true,
+		},
+		{
+			Type:    ExporterProcessType,
+			Command: toolPath,
+			Args:    []string{"exporter"},
+			Direct:  true,
+		},
+	}
+	logger.LaunchProcesses(processes)
+
+	return packit.BuildResult{
+		Launch: packit.LaunchMetadata{
+			Processes: processes,
+		},
+	}, nil
+}
+
+// phpRedisLinkedExtensions returns the extensions the phpredis extension is
+// built against, such as igbinary, which must be loaded for redis.so to load.
+// The redis.so of the PHP installation is loaded with php to find them, unless
+// PHP already loads it.
+func phpRedisLinkedExtensions(installation PhpInstallation, extensionInspector ExtensionInspector) ([]string, error) {
+	if installation.Loaded("redis") {
+		return nil, nil
+	}
+
+	return extensionInspector.RequiredExtensions("redis.so")
+}
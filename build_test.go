@@ -3,6 +3,7 @@ package phpredishandler_test
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -29,6 +30,10 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		configParser         *fakes.ConfigParser
 		buildBindingResolver *fakes.BuildBindingResolver
 		configWriter         *fakes.ConfigWriter
+		memcachedParser      *fakes.MemcachedParser
+		memcachedWriter      *fakes.MemcachedWriter
+		extensionInspector   *fakes.ExtensionInspector
+		environment          phpredishandler.Environment
 
 		parsedRedisConfig phpredishandler.RedisConfig
 
@@ -46,6 +51,9 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 		workingDir, err = os.MkdirTemp("", "working-dir")
 		Expect(err).NotTo(HaveOccurred())
 
+		Expect(os.MkdirAll(filepath.Join(cnbDir, "bin"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(cnbDir, "bin", "php-redis-session"), []byte("some-tool"), 0755)).To(Succeed())
+
 		buffer = bytes.NewBuffer(nil)
 		logEmitter := scribe.NewEmitter(buffer)
 
@@ -65,7 +73,22 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 
 		configParser.ParseCall.Returns.RedisConfig = parsedRedisConfig
 
-		build = phpredishandler.Build(configParser, buildBindingResolver, configWriter, logEmitter)
+		Expect(os.WriteFile(filepath.Join(workingDir, "redis.so"), nil, os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "igbinary.so"), nil, os.ModePerm)).To(Succeed())
+
+		extensionInspector = &fakes.ExtensionInspector{}
+		extensionInspector.InspectCall.Returns.PhpInstallation = phpredishandler.PhpInstallation{
+			Version:          "8.1.12",
+			ExtensionDir:     workingDir,
+			LoadedExtensions: []string{"Core", "json"},
+		}
+
+		environment = phpredishandler.Environment{}
+
+		memcachedParser = &fakes.MemcachedParser{}
+		memcachedWriter = &fakes.MemcachedWriter{}
+
+		build = phpredishandler.Build(configParser, memcachedParser, buildBindingResolver, configWriter, memcachedWriter, extensionInspector, environment, logEmitter)
 	})
 
 	it.After(func() {
@@ -82,7 +105,7 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			Platform: packit.Platform{
 				Path: "some-platform-path",
 			},
-			CNBPath: "some-cnb-path",
+			CNBPath: cnbDir,
 		})
 		Expect(err).NotTo(HaveOccurred())
 
@@ -96,6 +119,8 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 			"PHP_INI_SCAN_DIR.append": filepath.Join(layerDir, "php-redis-config"),
 			"PHP_INI_SCAN_DIR.delim":  ":",
 		}))
+		Expect(layer.ExecD).To(Equal([]string{filepath.Join(cnbDir, "bin", "session-switch")}))
+		Expect(filepath.Join(layerDir, "php-redis-config", "disabled", "php-redis-disabled.ini")).To(BeARegularFile())
 
 		Expect(buildBindingResolver.ResolveOneCall.Receives.Typ).To(Equal("php-redis-session"))
 		Expect(buildBindingResolver.ResolveOneCall.Receives.Provider).To(Equal(""))
@@ -103,15 +128,98 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 
 		Expect(configParser.ParseCall.Receives.Dir).To(Equal("some-binding-path"))
 
+		Expect(extensionInspector.InspectCall.CallCount).To(Equal(1))
+
 		Expect(configWriter.WriteCall.Receives.RedisConfig).To(Equal(parsedRedisConfig))
+		Expect(configWriter.WriteCall.Receives.Extensions).To(Equal([]string{"redis.so"}))
 		Expect(configWriter.WriteCall.Receives.LayerPath).To(Equal(filepath.Join(layerDir, "php-redis-config")))
-		Expect(configWriter.WriteCall.Receives.CnbPath).To(Equal("some-cnb-path"))
+		Expect(configWriter.WriteCall.Receives.TemplatePath).To(Equal(filepath.Join(cnbDir, "config", "php-redis.ini")))
+
+		toolPath := filepath.Join(layerDir, "php-redis-config", "bin", "php-redis-session")
+		Expect(toolPath).To(BeARegularFile())
+		Expect(result.Launch.Processes).To(Equal([]packit.Process{
+			{
+				Type:    "redis-session-migrate",
+				Command: toolPath,
+				Args:    []string{"migrate"},
+				Direct:  true,
+			},
+			{
+				Type:    "redis-session-exporter",
+				Command: toolPath,
+				Args:    []string{"exporter"},
+				Direct:  true,
+			},
+		}))
+
+		Expect(buffer.String()).To(ContainSubstring("Installing the php-redis-session tool"))
 	})
 
-	context("failure cases", func() {
-		context("when the redis layer cannot be retrieved", func() {
+	context("when there is a valkey binding", func() {
+		it.Before(func() {
+			buildBindingResolver.ResolveCall.Stub = func(typ, provider, platformDir string) ([]servicebindings.Binding, error) {
+				if typ == "valkey" {
+					return []servicebindings.Binding{{Type: typ, Path: "some-valkey-binding-path"}}, nil
+				}
+				return nil, nil
+			}
+			buildBindingResolver.ResolveOneCall.Returns.Binding = servicebindings.Binding{
+				Type: "valkey",
+				Path: "some-valkey-binding-path",
+			}
+		})
+
+		it("renders the redis configuration and logs the valkey flavor", func() {
+			_, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				Platform: packit.Platform{
+					Path: "some-platform-path",
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buildBindingResolver.ResolveOneCall.Receives.Typ).To(Equal("valkey"))
+			Expect(configParser.ParseCall.Receives.Dir).To(Equal("some-valkey-binding-path"))
+			Expect(configWriter.WriteCall.Receives.RedisConfig).To(Equal(parsedRedisConfig))
+
+			Expect(buffer.String()).To(ContainSubstring("Configuring the valkey session backend"))
+			Expect(buffer.String()).To(ContainSubstring("Using the valkey service binding"))
+		})
+
+		context("when there is also a php-redis-session binding", func() {
 			it.Before(func() {
-				Expect(os.Chmod(layerDir, 0000)).To(Succeed())
+				buildBindingResolver.ResolveCall.Stub = func(typ, provider, platformDir string) ([]servicebindings.Binding, error) {
+					if typ == "valkey" || typ == "php-redis-session" {
+						return []servicebindings.Binding{{Type: typ}}, nil
+					}
+					return nil, nil
+				}
+			})
+
+			it("prefers the php-redis-session binding", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(buildBindingResolver.ResolveOneCall.Receives.Typ).To(Equal("php-redis-session"))
+			})
+		})
+
+		context("when there is also a php-valkey-session binding", func() {
+			it.Before(func() {
+				buildBindingResolver.ResolveCall.Stub = func(typ, provider, platformDir string) ([]servicebindings.Binding, error) {
+					if typ == "php-valkey-session" || typ == "php-redis-session" {
+						return []servicebindings.Binding{{Type: typ}}, nil
+					}
+					return nil, nil
+				}
 			})
 
 			it("returns an error", func() {
@@ -119,19 +227,126 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 					Layers: packit.Layers{
 						Path: layerDir,
 					},
+					CNBPath: cnbDir,
 				})
-				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+				Expect(err).To(MatchError("found bindings of type php-redis-session and php-valkey-session: only one session backend can be configured"))
 			})
 		})
+	})
 
-		context("when the redis layer cannot be reset", func() {
+	context("when BP_PHP_REDIS_SESSION_BINDING_TYPES is set", func() {
+		var resolvedTypes []string
+
+		it.Before(func() {
+			environment["BP_PHP_REDIS_SESSION_BINDING_TYPES"] = "some-session-type, other-session-type"
+
+			resolvedTypes = nil
+			buildBindingResolver.ResolveCall.Stub = func(typ, provider, platformDir string) ([]servicebindings.Binding, error) {
+				resolvedTypes = append(resolvedTypes, typ)
+				if typ == "other-session-type" {
+					return []servicebindings.Binding{{Type: typ}}, nil
+				}
+				return nil, nil
+			}
+		})
+
+		it("only accepts the configured binding types", func() {
+			_, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(resolvedTypes).To(Equal([]string{"some-session-type", "other-session-type", "php-memcached-session"}))
+			Expect(buildBindingResolver.ResolveOneCall.Receives.Typ).To(Equal("other-session-type"))
+			Expect(buffer.String()).To(ContainSubstring("Configuring the redis session backend"))
+		})
+	})
+
+	context("when there is a php-memcached-session binding", func() {
+		var memcachedConfig phpredishandler.MemcachedConfig
+
+		it.Before(func() {
+			buildBindingResolver.ResolveCall.Stub = func(typ, provider, platformDir string) ([]servicebindings.Binding, error) {
+				if typ == "php-memcached-session" {
+					return []servicebindings.Binding{{Type: typ, Path: "some-memcached-binding-path"}}, nil
+				}
+				return nil, nil
+			}
+			buildBindingResolver.ResolveOneCall.Returns.Binding = servicebindings.Binding{
+				Path: "some-memcached-binding-path",
+			}
+
+			memcachedConfig = phpredishandler.MemcachedConfig{
+				Servers: []phpredishandler.MemcachedServer{{Hostname: "some-host", Port: 11211}},
+			}
+			memcachedParser.ParseCall.Returns.MemcachedConfig = memcachedConfig
+			memcachedWriter.WriteCall.Returns.String = "some-memcached-ini-path"
+
+			Expect(os.WriteFile(filepath.Join(workingDir, "memcached.so"), nil, os.ModePerm)).To(Succeed())
+		})
+
+		it("writes a memcached configuration into the shared layer", func() {
+			result, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				Platform: packit.Platform{
+					Path: "some-platform-path",
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(1))
+			layer := result.Layers[0]
+			Expect(layer.Name).To(Equal("php-redis-config"))
+			Expect(layer.Launch).To(BeTrue())
+			Expect(layer.LaunchEnv).To(Equal(packit.Environment{
+				"PHP_INI_SCAN_DIR.append": filepath.Join(layerDir, "php-redis-config"),
+				"PHP_INI_SCAN_DIR.delim":  ":",
+			}))
+			Expect(result.Launch.Processes).To(BeEmpty())
+
+			Expect(buildBindingResolver.ResolveOneCall.Receives.Typ).To(Equal("php-memcached-session"))
+			Expect(memcachedParser.ParseCall.Receives.Dir).To(Equal("some-memcached-binding-path"))
+			Expect(configParser.ParseCall.CallCount).To(Equal(0))
+
+			Expect(memcachedWriter.WriteCall.Receives.MemcachedConfig).To(Equal(memcachedConfig))
+			Expect(memcachedWriter.WriteCall.Receives.Extensions).To(Equal([]string{"memcached.so"}))
+			Expect(memcachedWriter.WriteCall.Receives.LayerPath).To(Equal(filepath.Join(layerDir, "php-redis-config")))
+			Expect(memcachedWriter.WriteCall.Receives.CnbPath).To(Equal(cnbDir))
+			Expect(configWriter.WriteCall.CallCount).To(Equal(0))
+
+			Expect(buffer.String()).To(ContainSubstring("Writing the memcached configuration"))
+		})
+
+		context("when there is also a php-redis-session binding", func() {
 			it.Before(func() {
-				Expect(os.MkdirAll(filepath.Join(layerDir, "php-redis-config", "some-dir"), os.ModePerm)).To(Succeed())
-				Expect(os.Chmod(filepath.Join(layerDir, "php-redis-config"), 0500)).To(Succeed())
+				buildBindingResolver.ResolveCall.Stub = func(typ, provider, platformDir string) ([]servicebindings.Binding, error) {
+					if typ == "php-redis-session" || typ == "php-memcached-session" {
+						return []servicebindings.Binding{{Type: typ}}, nil
+					}
+					return nil, nil
+				}
 			})
 
-			it.After(func() {
-				Expect(os.Chmod(filepath.Join(layerDir, "php-redis-config"), os.ModePerm)).To(Succeed())
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).To(MatchError("found bindings of type php-redis-session and php-memcached-session: only one session backend can be configured"))
+			})
+		})
+
+		context("when the PHP installation does not provide memcached", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "memcached.so"))).To(Succeed())
 			})
 
 			it("returns an error", func() {
@@ -139,14 +354,15 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 					Layers: packit.Layers{
 						Path: layerDir,
 					},
+					CNBPath: cnbDir,
 				})
-				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+				Expect(err).To(MatchError(ContainSubstring("PHP 8.1.12 does not provide the memcached extension")))
 			})
 		})
 
-		context("when the redis binding cannot be resolved", func() {
+		context("when the binding cannot be parsed", func() {
 			it.Before(func() {
-				buildBindingResolver.ResolveOneCall.Returns.Error = errors.New("failed to resolve php-redis-session binding")
+				memcachedParser.ParseCall.Returns.Error = errors.New("failed to parse")
 			})
 
 			it("returns an error", func() {
@@ -154,14 +370,15 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 					Layers: packit.Layers{
 						Path: layerDir,
 					},
+					CNBPath: cnbDir,
 				})
-				Expect(err).To(MatchError("failed to resolve php-redis-session binding"))
+				Expect(err).To(MatchError("failed to parse"))
 			})
 		})
 
-		context("when the redis binding cannot be parsed", func() {
+		context("when the configuration cannot be written", func() {
 			it.Before(func() {
-				configParser.ParseCall.Returns.Error = errors.New("failed to parse binding")
+				memcachedWriter.WriteCall.Returns.Error = errors.New("failed to write")
 			})
 
 			it("returns an error", func() {
@@ -169,14 +386,72 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 					Layers: packit.Layers{
 						Path: layerDir,
 					},
+					CNBPath: cnbDir,
 				})
-				Expect(err).To(MatchError("failed to parse binding"))
+				Expect(err).To(MatchError("failed to write"))
+			})
+		})
+	})
+
+	context("when the PHP installation already loads an extension", func() {
+		it.Before(func() {
+			environment["BP_PHP_REDIS_SESSION_SERIALIZER"] = "igbinary"
+			extensionInspector.InspectCall.Returns.PhpInstallation.LoadedExtensions = []string{"Core", "igbinary"}
+		})
+
+		it("only loads the remaining extensions", func() {
+			_, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
 			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(configWriter.WriteCall.Receives.Extensions).To(Equal([]string{"redis.so"}))
+			Expect(buffer.String()).To(ContainSubstring("Extension igbinary is already loaded"))
 		})
+	})
 
-		context("when the redis configuration cannot be written", func() {
+	context("when redis.so is built against igbinary", func() {
+		it.Before(func() {
+			extensionInspector.RequiredExtensionsCall.Returns.StringSlice = []string{"igbinary"}
+		})
+
+		it("loads igbinary before redis", func() {
+			_, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(extensionInspector.RequiredExtensionsCall.Receives.Extension).To(Equal("redis.so"))
+			Expect(configWriter.WriteCall.Receives.Extensions).To(Equal([]string{"igbinary.so", "redis.so"}))
+		})
+
+		context("when PHP already loads redis", func() {
 			it.Before(func() {
-				configWriter.WriteCall.Returns.Error = errors.New("failed to write config")
+				extensionInspector.InspectCall.Returns.PhpInstallation.LoadedExtensions = []string{"Core", "igbinary", "redis"}
+			})
+
+			it("does not load redis.so to inspect it", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(extensionInspector.RequiredExtensionsCall.CallCount).To(Equal(0))
+			})
+		})
+
+		context("when redis.so cannot be inspected", func() {
+			it.Before(func() {
+				extensionInspector.RequiredExtensionsCall.Returns.Error = errors.New("failed to load the redis.so extension")
 			})
 
 			it("returns an error", func() {
@@ -184,8 +459,1100 @@ func testBuild(t *testing.T, context spec.G, it spec.S) {
 					Layers: packit.Layers{
 						Path: layerDir,
 					},
+					CNBPath: cnbDir,
 				})
-				Expect(err).To(MatchError("failed to write config"))
+				Expect(err).To(MatchError("failed to load the redis.so extension"))
+			})
+		})
+	})
+
+	context("when BP_PHP_REDIS_SESSION_CLIENT selects relay", func() {
+		it.Before(func() {
+			environment["BP_PHP_REDIS_SESSION_CLIENT"] = "relay"
+			Expect(os.WriteFile(filepath.Join(workingDir, "relay.so"), nil, os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "msgpack.so"), nil, os.ModePerm)).To(Succeed())
+		})
+
+		it("loads the relay extensions", func() {
+			_, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(configWriter.WriteCall.Receives.RedisConfig.Client).To(Equal("relay"))
+			Expect(configWriter.WriteCall.Receives.Extensions).To(Equal([]string{"relay.so", "igbinary.so", "msgpack.so"}))
+		})
+
+		context("when the PHP installation does not provide relay", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "relay.so"))).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("PHP 8.1.12 does not provide the relay extension")))
+			})
+		})
+	})
+
+	context("when the PHP installation does not provide the phpredis extensions", func() {
+		it.Before(func() {
+			Expect(os.Remove(filepath.Join(workingDir, "redis.so"))).To(Succeed())
+
+			Expect(os.MkdirAll(filepath.Join(workingDir, "vendor", "predis", "predis"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "vendor", "predis", "predis", "autoload.php"), nil, os.ModePerm)).To(Succeed())
+			configWriter.WritePredisHandlerCall.Returns.String = "some-handler-path"
+		})
+
+		it("falls back to the predis session client", func() {
+			result, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath:    cnbDir,
+				WorkingDir: workingDir,
+				Stack:      "some-stack",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers).To(HaveLen(1))
+
+			Expect(configWriter.WritePredisHandlerCall.Receives.RedisConfig.Client).To(Equal("predis"))
+			Expect(configWriter.WritePredisHandlerCall.Receives.PredisPath).To(Equal(filepath.Join(workingDir, "vendor", "predis", "predis")))
+			Expect(configWriter.WritePredisHandlerCall.Receives.LayerPath).To(Equal(filepath.Join(layerDir, "php-redis-config")))
+			Expect(configWriter.WritePredisHandlerCall.Receives.CnbPath).To(Equal(cnbDir))
+
+			Expect(configWriter.WriteCall.Receives.RedisConfig.Client).To(Equal("predis"))
+			Expect(configWriter.WriteCall.Receives.Extensions).To(BeEmpty())
+
+			Expect(buffer.String()).To(ContainSubstring("Falling back to the predis session client: PHP 8.1.12 does not provide the redis extension"))
+			Expect(buffer.String()).To(ContainSubstring("Using the application's Predis: vendor/predis/predis"))
+		})
+
+		context("when the application does not require predis/predis", func() {
+			it.Before(func() {
+				Expect(os.RemoveAll(filepath.Join(workingDir, "vendor"))).To(Succeed())
+			})
+
+			it("reports the missing extension and why predis cannot be used", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("PHP 8.1.12 does not provide the redis extension")))
+				Expect(err).To(MatchError(ContainSubstring("and the predis session client cannot be used instead: the application does not require predis/predis with Composer")))
+				Expect(configWriter.WritePredisHandlerCall.CallCount).To(Equal(0))
+			})
+		})
+
+		context("when session locking is enabled", func() {
+			it.Before(func() {
+				environment["BP_PHP_REDIS_SESSION_LOCKING_ENABLED"] = "true"
+			})
+
+			it("does not fall back to predis, which has no session locking", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("PHP 8.1.12 does not provide the redis extension")))
+				Expect(configWriter.WritePredisHandlerCall.CallCount).To(Equal(0))
+			})
+		})
+
+		context("when the Predis session handler cannot be written", func() {
+			it.Before(func() {
+				configWriter.WritePredisHandlerCall.Returns.Error = errors.New("failed to write handler")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError("failed to write handler"))
+			})
+		})
+	})
+
+	context("when BP_PHP_REDIS_SESSION_CLIENT selects predis", func() {
+		it.Before(func() {
+			environment["BP_PHP_REDIS_SESSION_CLIENT"] = "predis"
+
+			Expect(os.MkdirAll(filepath.Join(workingDir, "vendor", "predis", "predis"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "vendor", "predis", "predis", "autoload.php"), nil, os.ModePerm)).To(Succeed())
+		})
+
+		it("uses the predis session client even though the extensions exist", func() {
+			_, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath:    cnbDir,
+				WorkingDir: workingDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(configWriter.WritePredisHandlerCall.CallCount).To(Equal(1))
+			Expect(configWriter.WriteCall.Receives.RedisConfig.Client).To(Equal("predis"))
+			Expect(configWriter.WriteCall.Receives.Extensions).To(BeEmpty())
+		})
+
+		context("when the application does not require predis/predis", func() {
+			it.Before(func() {
+				Expect(os.RemoveAll(filepath.Join(workingDir, "vendor"))).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath:    cnbDir,
+					WorkingDir: workingDir,
+				})
+				Expect(err).To(MatchError("the application does not require predis/predis with Composer: vendor/predis/predis/autoload.php is missing"))
+			})
+		})
+	})
+
+	context("when the locking settings are set in the build environment", func() {
+		it.Before(func() {
+			parsedRedisConfig.LockWaitTime = 1000
+			parsedRedisConfig.Sources = map[string]string{"lock_wait_time": "some-binding-path/lock_wait_time"}
+			configParser.ParseCall.Returns.RedisConfig = parsedRedisConfig
+
+			environment["BP_PHP_REDIS_SESSION_LOCKING_ENABLED"] = "true"
+			environment["BP_PHP_REDIS_SESSION_LOCK_WAIT_TIME"] = "2000"
+		})
+
+		it("overrides the binding", func() {
+			_, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(configWriter.WriteCall.Receives.RedisConfig.LockingEnabled).To(BeTrue())
+			Expect(configWriter.WriteCall.Receives.RedisConfig.LockWaitTime).To(Equal(2000))
+			Expect(configWriter.WriteCall.Receives.RedisConfig.Sources).To(HaveKeyWithValue("lock_wait_time", "BP_PHP_REDIS_SESSION_LOCK_WAIT_TIME"))
+		})
+	})
+
+	context("when the session lifetime is shorter than lock_expire", func() {
+		it.Before(func() {
+			environment["BP_PHP_REDIS_SESSION_LOCKING_ENABLED"] = "true"
+			environment["BP_PHP_REDIS_SESSION_LOCK_EXPIRE"] = "600"
+			environment["BP_PHP_REDIS_SESSION_GC_MAXLIFETIME"] = "300"
+		})
+
+		it("warns in the build log", func() {
+			_, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(configWriter.WriteCall.Receives.RedisConfig.GcMaxLifetime).To(Equal(300))
+			Expect(buffer.String()).To(ContainSubstring("Warning: gc_maxlifetime of 300s is shorter than the lock_expire of 600s"))
+		})
+	})
+
+	context("when igbinary is the serializer", func() {
+		it.Before(func() {
+			environment["BP_PHP_REDIS_SESSION_SERIALIZER"] = "igbinary"
+		})
+
+		it("loads the igbinary extension", func() {
+			_, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(configWriter.WriteCall.Receives.RedisConfig.Serializer).To(Equal("igbinary"))
+			Expect(configWriter.WriteCall.Receives.Extensions).To(Equal([]string{"redis.so", "igbinary.so"}))
+		})
+	})
+
+	context("when session compression is configured", func() {
+		it.Before(func() {
+			environment["BP_PHP_REDIS_SESSION_COMPRESSION"] = "zstd"
+			extensionInspector.RedisCompressionsCall.Returns.StringSlice = []string{"lzf", "zstd"}
+		})
+
+		it("verifies that phpredis supports it", func() {
+			_, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(extensionInspector.RedisCompressionsCall.Receives.Extensions).To(Equal([]string{"redis.so"}))
+			Expect(configWriter.WriteCall.Receives.RedisConfig.Compression).To(Equal("zstd"))
+			Expect(buffer.String()).To(ContainSubstring("phpredis supports zstd compression"))
+		})
+
+		context("when phpredis was built without it", func() {
+			it.Before(func() {
+				extensionInspector.RedisCompressionsCall.Returns.StringSlice = []string{"lzf"}
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).To(MatchError(`the phpredis extension of PHP 8.1.12 was built without zstd compression support: it supports ["lzf"]`))
+				Expect(configWriter.WriteCall.CallCount).To(Equal(0))
+			})
+		})
+
+		context("when the phpredis extension cannot be inspected", func() {
+			it.Before(func() {
+				extensionInspector.RedisCompressionsCall.Returns.Error = errors.New("failed to inspect phpredis")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).To(MatchError("failed to inspect phpredis"))
+			})
+		})
+	})
+
+	context("when the application provides a php-redis.ini template", func() {
+		it.Before(func() {
+			Expect(os.MkdirAll(filepath.Join(workingDir, ".php-redis-session"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, ".php-redis-session", "php-redis.ini.tmpl"), nil, os.ModePerm)).To(Succeed())
+		})
+
+		it("renders the application's template", func() {
+			_, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(configWriter.WriteCall.Receives.TemplatePath).To(Equal(filepath.Join(workingDir, ".php-redis-session", "php-redis.ini.tmpl")))
+			Expect(buffer.String()).To(ContainSubstring("Using the application's template: .php-redis-session/php-redis.ini.tmpl"))
+		})
+	})
+
+	context("when other ini files set the session settings", func() {
+		var phpIni string
+
+		it.Before(func() {
+			configWriter.WriteCall.Stub = func(_ phpredishandler.RedisConfig, _ []string, layerPath, _ string) (string, error) {
+				path := filepath.Join(layerPath, "php-redis.ini")
+				return path, os.WriteFile(path, []byte("session.save_handler = redis\nsession.name = PHPSESSID\n"), os.ModePerm)
+			}
+
+			phpIni = filepath.Join(workingDir, "php.ini")
+			Expect(os.WriteFile(phpIni, []byte("session.save_handler = files\nsession.name = PHPSESSID\n"), os.ModePerm)).To(Succeed())
+			extensionInspector.IniFilesCall.Returns.StringSlice = []string{phpIni}
+
+			Expect(os.MkdirAll(filepath.Join(workingDir, ".php.ini.d"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, ".php.ini.d", "app.ini"), []byte("session.name = APP\n"), os.ModePerm)).To(Succeed())
+		})
+
+		it("warns about the conflicting settings", func() {
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(buffer.String()).To(ContainSubstring("Checking for conflicting session settings"))
+			Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf(`Warning: %s:1 sets session.save_handler to "files" instead of "redis"`, phpIni)))
+			Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf(`Warning: %s:1 sets session.name to "APP" instead of "PHPSESSID"`, filepath.Join(workingDir, ".php.ini.d", "app.ini"))))
+			Expect(result.Layers[0].LaunchEnv).NotTo(HaveKey("BPL_PHP_REDIS_SESSION_INI_LAST.default"))
+		})
+
+		context("when the policy is fail", func() {
+			it.Before(func() {
+				environment["BP_PHP_REDIS_SESSION_INI_CONFLICTS"] = "fail"
+			})
+
+			it("returns an error listing the conflicts", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).To(MatchError(fmt.Sprintf("found ini files that set the session settings of the php-redis-config layer to different values:\n  %s:1 sets session.save_handler to \"files\" instead of \"redis\"\n  %s:1 sets session.name to \"APP\" instead of \"PHPSESSID\"", phpIni, filepath.Join(workingDir, ".php.ini.d", "app.ini"))))
+			})
+		})
+
+		context("when the policy is override", func() {
+			it.Before(func() {
+				environment["BP_PHP_REDIS_SESSION_INI_CONFLICTS"] = "override"
+			})
+
+			it("loads the layer last at launch and reports the settings that take precedence", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				layer := result.Layers[0]
+				Expect(layer.LaunchEnv).To(HaveKeyWithValue("BPL_PHP_REDIS_SESSION_INI_LAST.default", "true"))
+				Expect(layer.ExecD).To(Equal([]string{filepath.Join(cnbDir, "bin", "session-switch")}))
+
+				Expect(buffer.String()).To(ContainSubstring("Loading the php-redis-config layer after the other ini files at launch"))
+				Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf(`Using session.save_handler = "redis" instead of "files" from %s:1`, phpIni)))
+				Expect(buffer.String()).NotTo(ContainSubstring("Warning"))
+			})
+
+			context("when no conflicts are found at build time", func() {
+				it.Before(func() {
+					extensionInspector.IniFilesCall.Returns.StringSlice = nil
+					Expect(os.RemoveAll(filepath.Join(workingDir, ".php.ini.d"))).To(Succeed())
+				})
+
+				it("still loads the layer last at launch", func() {
+					result, err := build(packit.BuildContext{
+						WorkingDir: workingDir,
+						Layers: packit.Layers{
+							Path: layerDir,
+						},
+						CNBPath: cnbDir,
+					})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.Layers[0].LaunchEnv).To(HaveKeyWithValue("BPL_PHP_REDIS_SESSION_INI_LAST.default", "true"))
+				})
+			})
+		})
+
+		context("when the policy is unknown", func() {
+			it.Before(func() {
+				environment["BP_PHP_REDIS_SESSION_INI_CONFLICTS"] = "ignore"
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring(`invalid BP_PHP_REDIS_SESSION_INI_CONFLICTS "ignore"`)))
+			})
+		})
+
+		context("when the ini files cannot be listed", func() {
+			it.Before(func() {
+				extensionInspector.IniFilesCall.Returns.Error = errors.New("failed to list ini files")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).To(MatchError("failed to list ini files"))
+			})
+		})
+
+		context("when a conflicting save path contains a password", func() {
+			it.Before(func() {
+				configWriter.WriteCall.Stub = func(_ phpredishandler.RedisConfig, _ []string, layerPath, _ string) (string, error) {
+					path := filepath.Join(layerPath, "php-redis.ini")
+					return path, os.WriteFile(path, []byte(`session.save_path = "tcp://some-host:1234?auth=some-password"`+"\n"), os.ModePerm)
+				}
+
+				Expect(os.WriteFile(phpIni, []byte(`session.save_path = "tcp://other-host:1234?auth=other-password"`+"\n"), os.ModePerm)).To(Succeed())
+				Expect(os.RemoveAll(filepath.Join(workingDir, ".php.ini.d"))).To(Succeed())
+			})
+
+			for _, policy := range []string{"warn", "fail", "override"} {
+				policy := policy
+
+				it(fmt.Sprintf("redacts the passwords with the %s policy", policy), func() {
+					environment["BP_PHP_REDIS_SESSION_INI_CONFLICTS"] = policy
+
+					_, err := build(packit.BuildContext{
+						WorkingDir: workingDir,
+						Layers: packit.Layers{
+							Path: layerDir,
+						},
+						CNBPath: cnbDir,
+					})
+					output := buffer.String()
+					if err != nil {
+						output += err.Error()
+					}
+
+					Expect(output).To(ContainSubstring("tcp://other-host:1234?auth=REDACTED"))
+					Expect(output).To(ContainSubstring("tcp://some-host:1234?auth=REDACTED"))
+					Expect(output).NotTo(ContainSubstring("some-password"))
+					Expect(output).NotTo(ContainSubstring("other-password"))
+				})
+			}
+		})
+	})
+
+	context("when the session configuration is scoped to web processes", func() {
+		it.Before(func() {
+			environment["BP_PHP_REDIS_SESSION_SCOPE"] = "web"
+			environment["BP_PHP_REDIS_SESSION_WEB_PROCESSES"] = "web, api"
+		})
+
+		it("loads the extensions everywhere and the session directives in the web processes", func() {
+			result, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			layerPath := filepath.Join(layerDir, "php-redis-config")
+			Expect(configWriter.WriteCall.Receives.Extensions).To(BeNil())
+			Expect(configWriter.WriteCall.Receives.LayerPath).To(Equal(filepath.Join(layerPath, "web")))
+
+			contents, err := os.ReadFile(filepath.Join(layerPath, "php-redis-extensions.ini"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("extension=redis.so\n"))
+
+			layer := result.Layers[0]
+			Expect(layer.LaunchEnv).To(Equal(packit.Environment{
+				"PHP_INI_SCAN_DIR.append": layerPath,
+				"PHP_INI_SCAN_DIR.delim":  ":",
+			}))
+			Expect(layer.ProcessLaunchEnv).To(Equal(map[string]packit.Environment{
+				"web": {
+					"PHP_INI_SCAN_DIR.append": filepath.Join(layerPath, "web"),
+					"PHP_INI_SCAN_DIR.delim":  ":",
+				},
+				"api": {
+					"PHP_INI_SCAN_DIR.append": filepath.Join(layerPath, "web"),
+					"PHP_INI_SCAN_DIR.delim":  ":",
+				},
+			}))
+
+			Expect(buffer.String()).To(ContainSubstring("Loading the session configuration in the web, api process types only"))
+		})
+	})
+
+	context("when process types have their own session settings", func() {
+		var written map[string]phpredishandler.RedisConfig
+
+		it.Before(func() {
+			environment["BP_PHP_REDIS_SESSION_PROCESSES"] = "worker"
+			environment["BP_PHP_REDIS_SESSION_GC_MAXLIFETIME"] = "1440"
+			environment["BP_PHP_REDIS_SESSION_PROCESS_WORKER_GC_MAXLIFETIME"] = "60"
+
+			written = map[string]phpredishandler.RedisConfig{}
+			configWriter.WriteCall.Stub = func(config phpredishandler.RedisConfig, extensions []string, layerPath, _ string) (string, error) {
+				written[layerPath] = config
+				return filepath.Join(layerPath, "php-redis.ini"), nil
+			}
+		})
+
+		it("writes the configuration of each process type into its own directory", func() {
+			result, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			layerPath := filepath.Join(layerDir, "php-redis-config")
+			workerPath := filepath.Join(layerPath, "processes", "worker")
+			Expect(written).To(HaveLen(2))
+			Expect(written[layerPath].GcMaxLifetime).To(Equal(1440))
+			Expect(written[workerPath].GcMaxLifetime).To(Equal(60))
+			Expect(workerPath).To(BeADirectory())
+
+			Expect(configWriter.WriteCall.Receives.Extensions).To(BeNil())
+
+			Expect(result.Layers[0].ProcessLaunchEnv).To(Equal(map[string]packit.Environment{
+				"worker": {
+					"PHP_INI_SCAN_DIR.append": workerPath,
+					"PHP_INI_SCAN_DIR.delim":  ":",
+				},
+			}))
+
+			Expect(buffer.String()).To(ContainSubstring("Writing the redis configuration of the worker process type"))
+		})
+
+		context("when the session configuration is scoped to web processes", func() {
+			it.Before(func() {
+				environment["BP_PHP_REDIS_SESSION_SCOPE"] = "web"
+				environment["BP_PHP_REDIS_SESSION_PROCESSES"] = "web worker"
+			})
+
+			it("loads the configuration of the process type after the web configuration", func() {
+				result, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				layerPath := filepath.Join(layerDir, "php-redis-config")
+				Expect(result.Layers[0].ProcessLaunchEnv).To(Equal(map[string]packit.Environment{
+					"web": {
+						"PHP_INI_SCAN_DIR.append": filepath.Join(layerPath, "web") + ":" + filepath.Join(layerPath, "processes", "web"),
+						"PHP_INI_SCAN_DIR.delim":  ":",
+					},
+					"worker": {
+						"PHP_INI_SCAN_DIR.append": filepath.Join(layerPath, "processes", "worker"),
+						"PHP_INI_SCAN_DIR.delim":  ":",
+					},
+				}))
+			})
+		})
+
+		context("when a process type selects another session client", func() {
+			it.Before(func() {
+				environment["BP_PHP_REDIS_SESSION_PROCESS_WORKER_CLIENT"] = "relay"
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).To(MatchError("failed to configure the worker process type: it must use the phpredis session client and the same extensions as the other processes"))
+			})
+		})
+
+		context("when a setting of a process type is invalid", func() {
+			it.Before(func() {
+				environment["BP_PHP_REDIS_SESSION_PROCESS_WORKER_GC_MAXLIFETIME"] = "-1"
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to configure the worker process type: invalid gc_maxlifetime")))
+			})
+		})
+	})
+
+	context("when BP_PHP_REDIS_SESSION_SCOPE is unknown", func() {
+		it.Before(func() {
+			environment["BP_PHP_REDIS_SESSION_SCOPE"] = "cli"
+		})
+
+		it("returns an error", func() {
+			_, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).To(MatchError(`invalid BP_PHP_REDIS_SESSION_SCOPE "cli": must be "all" or "web"`))
+		})
+	})
+
+	context("when BP_PHP_REDIS_SESSION_FPM_ADMIN is set", func() {
+		it.Before(func() {
+			environment["BP_PHP_REDIS_SESSION_FPM_ADMIN"] = "true"
+			environment["BP_PHP_REDIS_SESSION_FPM_POOL"] = "app"
+			configWriter.WriteFpmPoolCall.Returns.String = "some-pool-path"
+		})
+
+		it("locks the session handler in the php-fpm pool", func() {
+			_, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(configWriter.WriteFpmPoolCall.CallCount).To(Equal(1))
+			Expect(configWriter.WriteFpmPoolCall.Receives.RedisConfig).To(Equal(parsedRedisConfig))
+			Expect(configWriter.WriteFpmPoolCall.Receives.Pool).To(Equal("app"))
+			Expect(configWriter.WriteFpmPoolCall.Receives.Dir).To(Equal(filepath.Join(layerDir, "php-redis-config")))
+			Expect(configWriter.WriteFpmPoolCall.Receives.CnbPath).To(Equal(cnbDir))
+
+			contents, err := os.ReadFile(filepath.Join(workingDir, ".php.fpm.d", "php-redis-session.conf"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(ContainSubstring("include=some-pool-path\n"))
+
+			Expect(buffer.String()).To(ContainSubstring("Locking the session handler in the app php-fpm pool"))
+			Expect(buffer.String()).To(ContainSubstring("php-fpm pool configuration written to: some-pool-path"))
+			Expect(buffer.String()).To(ContainSubstring(fmt.Sprintf("php-fpm pool include written to: %s", filepath.Join(workingDir, ".php.fpm.d", "php-redis-session.conf"))))
+		})
+
+		context("when the php-fpm configuration does not include the application's pool files", func() {
+			it.Before(func() {
+				fpmConfig := filepath.Join(t.TempDir(), "php-fpm.conf")
+				Expect(os.WriteFile(fpmConfig, []byte("[global]\n"), os.ModePerm)).To(Succeed())
+				environment["PHP_FPM_PATH"] = fpmConfig
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("does not include " + filepath.Join(workingDir, ".php.fpm.d", "php-redis-session.conf"))))
+			})
+		})
+
+		context("when the pool cannot be written", func() {
+			it.Before(func() {
+				configWriter.WriteFpmPoolCall.Returns.Error = errors.New("failed to write pool")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).To(MatchError("failed to write pool"))
+			})
+		})
+	})
+
+	context("when BP_PHP_REDIS_SESSION_BUILD is set", func() {
+		it.Before(func() {
+			environment["BP_PHP_REDIS_SESSION_BUILD"] = "true"
+		})
+
+		it("makes the session configuration available to the build", func() {
+			result, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			layer := result.Layers[0]
+			Expect(layer.Build).To(BeTrue())
+			Expect(layer.Launch).To(BeTrue())
+			Expect(layer.BuildEnv).To(Equal(packit.Environment{
+				"PHP_INI_SCAN_DIR.append": filepath.Join(layerDir, "php-redis-config"),
+				"PHP_INI_SCAN_DIR.delim":  ":",
+			}))
+		})
+
+		context("when the session configuration is scoped to web processes", func() {
+			it.Before(func() {
+				environment["BP_PHP_REDIS_SESSION_SCOPE"] = "web"
+			})
+
+			it("loads the web configuration during the build", func() {
+				result, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				layerPath := filepath.Join(layerDir, "php-redis-config")
+				Expect(result.Layers[0].BuildEnv).To(Equal(packit.Environment{
+					"PHP_INI_SCAN_DIR.append": layerPath + ":" + filepath.Join(layerPath, "web"),
+					"PHP_INI_SCAN_DIR.delim":  ":",
+				}))
+			})
+		})
+
+		context("when the value is not a boolean", func() {
+			it.Before(func() {
+				environment["BP_PHP_REDIS_SESSION_BUILD"] = "sometimes"
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).To(MatchError(`failed to parse BP_PHP_REDIS_SESSION_BUILD: "sometimes" is not a boolean`))
+			})
+		})
+	})
+
+	context("when BP_PHP_REDIS_SESSION_EXPORT_ENV is set", func() {
+		it.Before(func() {
+			environment["BP_PHP_REDIS_SESSION_EXPORT_ENV"] = "true"
+		})
+
+		it("exports the connection details as launch environment variables", func() {
+			result, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].LaunchEnv).To(Equal(packit.Environment{
+				"PHP_INI_SCAN_DIR.append": filepath.Join(layerDir, "php-redis-config"),
+				"PHP_INI_SCAN_DIR.delim":  ":",
+				"REDIS_HOST.default":      "some-hostname",
+				"REDIS_PORT.default":      "1234",
+				"REDIS_PASSWORD.default":  "some-password",
+				"REDIS_URL.default":       "redis://:some-password@some-hostname:1234",
+			}))
+
+			Expect(buffer.String()).To(ContainSubstring("Exporting the connection details for application code"))
+			Expect(buffer.String()).To(ContainSubstring("password: REDIS_PASSWORD"))
+			Expect(buffer.String()).To(MatchRegexp(`REDIS_PASSWORD +-> "REDACTED"`))
+			Expect(buffer.String()).To(MatchRegexp(`REDIS_URL +-> "REDACTED"`))
+			Expect(buffer.String()).NotTo(ContainSubstring("some-password"))
+		})
+
+		context("when the names are invalid", func() {
+			it.Before(func() {
+				environment["BP_PHP_REDIS_SESSION_EXPORT_NAMES"] = "REDIS_HOST"
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("invalid BP_PHP_REDIS_SESSION_EXPORT_NAMES entry")))
+			})
+		})
+	})
+
+	context("when the application uses a framework", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "composer.lock"), []byte(`{"packages": [{"name": "laravel/framework"}]}`), os.ModePerm)).To(Succeed())
+		})
+
+		it("sets the framework's session environment variables", func() {
+			result, err := build(packit.BuildContext{
+				WorkingDir: workingDir,
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(result.Layers[0].LaunchEnv).To(Equal(packit.Environment{
+				"PHP_INI_SCAN_DIR.append": filepath.Join(layerDir, "php-redis-config"),
+				"PHP_INI_SCAN_DIR.delim":  ":",
+				"SESSION_DRIVER.default":  "redis",
+				"REDIS_CLIENT.default":    "phpredis",
+				"REDIS_HOST.default":      "some-hostname",
+				"REDIS_PORT.default":      "1234",
+				"REDIS_PASSWORD.default":  "some-password",
+			}))
+
+			Expect(buffer.String()).To(ContainSubstring("Configuring the Laravel framework"))
+			Expect(buffer.String()).To(ContainSubstring("Detected from composer.lock"))
+			Expect(buffer.String()).To(ContainSubstring("Setting REDIS_CLIENT, REDIS_HOST, REDIS_PASSWORD, REDIS_PORT, SESSION_DRIVER"))
+			Expect(buffer.String()).To(MatchRegexp(`REDIS_PASSWORD +-> "REDACTED"`))
+			Expect(buffer.String()).NotTo(ContainSubstring("some-password"))
+		})
+
+		context("when BP_PHP_REDIS_SESSION_FRAMEWORK is none", func() {
+			it.Before(func() {
+				environment["BP_PHP_REDIS_SESSION_FRAMEWORK"] = "none"
+			})
+
+			it("does not set the framework's environment variables", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[0].LaunchEnv).NotTo(HaveKey("SESSION_DRIVER.default"))
+				Expect(buffer.String()).NotTo(ContainSubstring("Configuring the Laravel framework"))
+			})
+		})
+
+		context("when an exported variable has a different value", func() {
+			it.Before(func() {
+				environment["BP_PHP_REDIS_SESSION_EXPORT_ENV"] = "true"
+				environment["BP_PHP_REDIS_SESSION_FRAMEWORK"] = "symfony"
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).To(MatchError("BP_PHP_REDIS_SESSION_EXPORT_ENV and the Symfony integration set REDIS_URL to different values: rename it with BP_PHP_REDIS_SESSION_EXPORT_NAMES or set BP_PHP_REDIS_SESSION_FRAMEWORK=none"))
+			})
+		})
+
+		context("when an exported variable has the same value", func() {
+			it.Before(func() {
+				environment["BP_PHP_REDIS_SESSION_EXPORT_ENV"] = "true"
+			})
+
+			it("sets it once", func() {
+				result, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Layers[0].LaunchEnv).To(HaveKeyWithValue("REDIS_HOST.default", "some-hostname"))
+				Expect(result.Layers[0].LaunchEnv).To(HaveKeyWithValue("REDIS_URL.default", "redis://:some-password@some-hostname:1234"))
+			})
+		})
+
+		context("when BP_PHP_REDIS_SESSION_FRAMEWORK is invalid", func() {
+			it.Before(func() {
+				environment["BP_PHP_REDIS_SESSION_FRAMEWORK"] = "rails"
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					WorkingDir: workingDir,
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring(`invalid BP_PHP_REDIS_SESSION_FRAMEWORK: unknown framework "rails"`)))
+			})
+		})
+	})
+
+	context("when the hardened preset is enabled", func() {
+		it.Before(func() {
+			environment["BP_PHP_REDIS_SESSION_HARDENED"] = "true"
+			environment["BP_PHP_REDIS_SESSION_SID_LENGTH"] = "48"
+			extensionInspector.InspectCall.Returns.PhpInstallation.Version = "8.4.1"
+		})
+
+		it("summarizes the session cookie in the build log", func() {
+			_, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(configWriter.WriteCall.Receives.RedisConfig.Hardened).To(BeTrue())
+			Expect(buffer.String()).To(ContainSubstring("Session cookie: name=PHPSESSID domain=default path=default secure=On httponly=On samesite=Lax strict_mode=On"))
+			Expect(buffer.String()).To(ContainSubstring("Warning: sid_length and sid_bits_per_character are deprecated as of PHP 8.4"))
+		})
+	})
+
+	context("when BP_PHP_REDIS_SESSION_CLIENT is unknown", func() {
+		it.Before(func() {
+			environment["BP_PHP_REDIS_SESSION_CLIENT"] = "some-client"
+		})
+
+		it("returns an error", func() {
+			_, err := build(packit.BuildContext{
+				Layers: packit.Layers{
+					Path: layerDir,
+				},
+				CNBPath: cnbDir,
+			})
+			Expect(err).To(MatchError(ContainSubstring(`unknown session client "some-client"`)))
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the redis layer cannot be retrieved", func() {
+			it.Before(func() {
+				Expect(os.Chmod(layerDir, 0000)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+				})
+				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+			})
+		})
+
+		context("when the redis layer cannot be reset", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(layerDir, "php-redis-config", "some-dir"), os.ModePerm)).To(Succeed())
+				Expect(os.Chmod(filepath.Join(layerDir, "php-redis-config"), 0500)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Chmod(filepath.Join(layerDir, "php-redis-config"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+				})
+				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+			})
+		})
+
+		context("when the redis binding cannot be resolved", func() {
+			it.Before(func() {
+				buildBindingResolver.ResolveOneCall.Returns.Error = errors.New("failed to resolve php-redis-session binding")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+				})
+				Expect(err).To(MatchError("failed to resolve php-redis-session binding"))
+			})
+		})
+
+		context("when the redis binding cannot be parsed", func() {
+			it.Before(func() {
+				configParser.ParseCall.Returns.Error = errors.New("failed to parse binding")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+				})
+				Expect(err).To(MatchError("failed to parse binding"))
+			})
+		})
+
+		context("when the redis configuration cannot be written", func() {
+			it.Before(func() {
+				configWriter.WriteCall.Returns.Error = errors.New("failed to write config")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+				})
+				Expect(err).To(MatchError("failed to write config"))
+			})
+		})
+
+		context("when the PHP installation cannot be inspected", func() {
+			it.Before(func() {
+				extensionInspector.InspectCall.Returns.Error = errors.New("failed to inspect")
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).To(MatchError("failed to inspect"))
+			})
+		})
+
+		context("when the PHP installation does not provide an extension of the selected client", func() {
+			it.Before(func() {
+				environment["BP_PHP_REDIS_SESSION_CLIENT"] = "phpredis"
+				Expect(os.Remove(filepath.Join(workingDir, "redis.so"))).To(Succeed())
+			})
+
+			it("returns an error naming the PHP version", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).To(MatchError(fmt.Sprintf("PHP 8.1.12 does not provide the redis extension: redis.so was not found in %s", workingDir)))
+				Expect(configWriter.WriteCall.CallCount).To(Equal(0))
+			})
+		})
+
+		context("when the session tool cannot be installed", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(cnbDir, "bin", "php-redis-session"))).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := build(packit.BuildContext{
+					Layers: packit.Layers{
+						Path: layerDir,
+					},
+					CNBPath: cnbDir,
+				})
+				Expect(err).To(MatchError(ContainSubstring("no such file or directory")))
 			})
 		})
 	})
@@ -0,0 +1,537 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paketo-buildpacks/packit/v2/chronos"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+)
+
+type command struct {
+	name        string
+	description string
+	run         func(args []string, logger scribe.Emitter) error
+}
+
+var commands = []command{
+	{name: "migrate", description: "copy file-based PHP sessions into Redis", run: migrate},
+	{name: "copy", description: "copy sessions between two Redis instances", run: copySessions},
+	{name: "exporter", description: "serve session storage metrics in the Prometheus text format", run: exporter},
+	{name: "doctor", description: "explain how the session configuration is resolved", run: doctor},
+	{name: "render", description: "print the php-redis.ini rendered from a binding", run: render},
+}
+
+func main() {
+	logger := scribe.NewEmitter(os.Stdout).WithLevel(os.Getenv("BP_LOG_LEVEL"))
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			err := c.run(os.Args[2:], logger)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", c.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", phpredishandler.SessionToolName)
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.description)
+	}
+}
+
+// loadRedisConfig parses the binding at the given path, or resolves the
+// binding of the first Redis binding type that has one when no path is given.
+func loadRedisConfig(bindingPath string) (phpredishandler.RedisConfig, error) {
+	if bindingPath == "" {
+		resolver := servicebindings.NewResolver()
+		types := phpredishandler.RedisBindingTypes(phpredishandler.LoadEnvironment(os.Environ()))
+
+		bindingType := ""
+		for _, typ := range types {
+			bindings, err := resolver.Resolve(typ, "", "")
+			if err != nil {
+				return phpredishandler.RedisConfig{}, err
+			}
+
+			if len(bindings) > 0 {
+				bindingType = typ
+				break
+			}
+		}
+
+		if bindingType == "" {
+			bindingType = types[0]
+		}
+
+		binding, err := resolver.ResolveOne(bindingType, "", "")
+		if err != nil {
+			return phpredishandler.RedisConfig{}, err
+		}
+
+		bindingPath = binding.Path
+	}
+
+	return phpredishandler.NewRedisConfigParser().Parse(bindingPath)
+}
+
+func migrate(args []string, logger scribe.Emitter) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	bindingPath := flags.String("binding", "", "path to a php-redis-session binding (defaults to resolving it from SERVICE_BINDING_ROOT)")
+	savePath := flags.String("save-path", "", "session.save_path of the files session handler")
+	maxLifetime := flags.Int("gc-maxlifetime", 1440, "session.gc_maxlifetime in seconds, used to compute the remaining TTL (defaults to the gc_maxlifetime of the binding, if set)")
+	timeout := flags.Duration("timeout", 5*time.Second, "timeout when connecting to redis")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *savePath == "" {
+		return fmt.Errorf("--save-path is required")
+	}
+
+	redisConfig, err := loadRedisConfig(*bindingPath)
+	if err != nil {
+		return err
+	}
+
+	// Sessions keep the lifetime the session handler gives them unless the
+	// flag says otherwise.
+	maxLifetimeSet := false
+	flags.Visit(func(f *flag.Flag) {
+		maxLifetimeSet = maxLifetimeSet || f.Name == "gc-maxlifetime"
+	})
+	if !maxLifetimeSet && redisConfig.GcMaxLifetime > 0 {
+		*maxLifetime = redisConfig.GcMaxLifetime
+	}
+
+	client, err := phpredishandler.DialRedis(redisConfig, *timeout)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	logger.Process("Migrating sessions from %s", *savePath)
+	report, err := phpredishandler.NewSessionMigrator(chronos.DefaultClock).Migrate(client, redisConfig.SessionKeyPrefix(), *savePath, time.Duration(*maxLifetime)*time.Second)
+	if err != nil {
+		return err
+	}
+
+	logger.Subprocess("Migrated %d session(s) with prefix %s", report.Migrated, redisConfig.SessionKeyPrefix())
+	logger.Subprocess("Skipped %d session(s)", len(report.Skipped))
+	for _, skipped := range report.Skipped {
+		logger.Action("%s: %s", skipped.Path, skipped.Reason)
+	}
+
+	return nil
+}
+
+func copySessions(args []string, logger scribe.Emitter) error {
+	flags := flag.NewFlagSet("copy", flag.ContinueOnError)
+	from := flags.String("from", "", "path to the php-redis-session binding of the source Redis")
+	to := flags.String("to", "", "path to the php-redis-session binding of the destination Redis")
+	method := flags.String("method", phpredishandler.CopyMethodDump, "copy keys with \"dump\" (DUMP/RESTORE) or \"get-set\" (GET/SET)")
+	dryRun := flags.Bool("dry-run", false, "report what would be copied without writing to the destination")
+	progress := flags.Int("progress", 1000, "number of keys between progress reports")
+	timeout := flags.Duration("timeout", 5*time.Second, "timeout when connecting to redis")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *from == "" || *to == "" {
+		return fmt.Errorf("--from and --to are required")
+	}
+
+	parser := phpredishandler.NewRedisConfigParser()
+	sourceConfig, err := parser.Parse(*from)
+	if err != nil {
+		return fmt.Errorf("failed to parse source binding: %w", err)
+	}
+
+	destinationConfig, err := parser.Parse(*to)
+	if err != nil {
+		return fmt.Errorf("failed to parse destination binding: %w", err)
+	}
+
+	source, err := phpredishandler.DialRedis(sourceConfig, *timeout)
+	if err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+
+	destination, err := phpredishandler.DialRedis(destinationConfig, *timeout)
+	if err != nil {
+		return fmt.Errorf("destination: %w", err)
+	}
+	defer func() {
+		_ = destination.Close()
+	}()
+
+	if *dryRun {
+		logger.Process("Dry run: no sessions will be written")
+	}
+
+	logger.Process("Copying sessions from %s:%d to %s:%d", sourceConfig.Hostname, sourceConfig.Port, destinationConfig.Hostname, destinationConfig.Port)
+	report, err := phpredishandler.NewSessionCopier(logger).Copy(source, destination, sourceConfig.SessionKeyPrefix(), destinationConfig.SessionKeyPrefix(), phpredishandler.SessionCopyOptions{
+		Method:           *method,
+		DryRun:           *dryRun,
+		ProgressInterval: *progress,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		logger.Subprocess("Would copy %d session(s)", report.Copied)
+	} else {
+		logger.Subprocess("Copied %d session(s)", report.Copied)
+	}
+	logger.Subprocess("Skipped %d session(s) that expired during the copy", report.Expired)
+
+	return nil
+}
+
+func exporter(args []string, logger scribe.Emitter) error {
+	port := os.Getenv("BPL_PHP_REDIS_SESSION_EXPORTER_PORT")
+	if port == "" {
+		port = "9180"
+	}
+
+	sizeSamples := 0
+	if value := os.Getenv("BPL_PHP_REDIS_SESSION_EXPORTER_SIZE_SAMPLES"); value != "" {
+		var err error
+		sizeSamples, err = strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse BPL_PHP_REDIS_SESSION_EXPORTER_SIZE_SAMPLES: %w", err)
+		}
+	}
+
+	flags := flag.NewFlagSet("exporter", flag.ContinueOnError)
+	bindingPath := flags.String("binding", "", "path to a php-redis-session binding (defaults to resolving it from SERVICE_BINDING_ROOT)")
+	flags.StringVar(&port, "port", port, "port to serve metrics on (defaults to $BPL_PHP_REDIS_SESSION_EXPORTER_PORT or 9180)")
+	interval := flags.Duration("interval", 30*time.Second, "interval between samples")
+	timeout := flags.Duration("timeout", 5*time.Second, "timeout when connecting to redis and for the PING of each sample")
+	scanTimeout := flags.Duration("scan-timeout", 20*time.Second, "timeout for counting the session keys of each sample")
+	flags.IntVar(&sizeSamples, "size-samples", sizeSamples, "number of session keys sized with MEMORY USAGE per sample to estimate their memory (defaults to $BPL_PHP_REDIS_SESSION_EXPORTER_SIZE_SAMPLES or 0, which disables sizing)")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	redisConfig, err := loadRedisConfig(*bindingPath)
+	if err != nil {
+		return err
+	}
+
+	metricsExporter := phpredishandler.NewSessionMetricsExporter(redisConfig, *timeout, chronos.DefaultClock).WithScanTimeout(*scanTimeout).WithSizeSamples(sizeSamples)
+	go metricsExporter.Run(*interval, nil)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metricsExporter)
+
+	logger.Process("Serving session metrics on :%s/metrics every %s", port, *interval)
+	return http.ListenAndServe(":"+port, mux)
+}
+
+func doctor(args []string, logger scribe.Emitter) error {
+	// The tool is installed into <layer>/bin, next to the rendered php-redis.ini.
+	var layerPath string
+	executable, err := os.Executable()
+	if err == nil {
+		layerPath = filepath.Dir(filepath.Dir(executable))
+	}
+
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	platformPath := flags.String("platform", os.Getenv("CNB_PLATFORM_DIR"), "platform directory used to resolve bindings when SERVICE_BINDING_ROOT is not set")
+	cnbPath := flags.String("cnb-path", "", "buildpack directory used to render php-redis.ini from the current binding")
+	workingDir := flags.String("working-dir", ".", "application directory that may contain "+phpredishandler.CustomRedisIniTemplate)
+	flags.StringVar(&layerPath, "layer", layerPath, "php-redis-config layer containing the php-redis.ini rendered at build time")
+
+	err = flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	var templatePath string
+	if *cnbPath != "" {
+		templatePath, err = phpredishandler.RedisIniTemplate(*workingDir, *cnbPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	resolver := servicebindings.NewResolver()
+	report := phpredishandler.NewDoctor(
+		resolver,
+		resolver,
+		phpredishandler.NewRedisConfigParser(),
+		phpredishandler.NewRedisConfigWriter(scribe.NewEmitter(io.Discard)),
+		phpredishandler.LoadEnvironment(os.Environ()),
+	).Diagnose(*platformPath, templatePath, layerPath)
+
+	logger.Process("Detection")
+	if report.Detected {
+		logger.Subprocess("Passed")
+	} else {
+		logger.Subprocess("Failed: %s", report.DetectMessage)
+	}
+	logger.Break()
+
+	logger.Process("Bindings")
+	for _, binding := range report.Bindings {
+		logger.Subprocess("%s (type %s) at %s", binding.Name, binding.Type, binding.Path)
+	}
+	logger.Subprocess("Selected: %s", report.SelectedBinding)
+	logger.Break()
+
+	logger.Process("Settings")
+	var names []string
+	for name := range report.Config.Sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		logger.Subprocess("%s = %s (from %s)", name, settingValue(report.Config, name), report.Config.Sources[name])
+	}
+	logger.Break()
+
+	logger.Process("PHP extensions")
+	for _, extension := range report.Extensions {
+		status := "missing"
+		if extension.Found {
+			status = "found"
+		}
+		logger.Subprocess("%s: %s (%s)", extension.Name, status, extension.Path)
+	}
+	logger.Break()
+
+	logger.Process("PHP_INI_SCAN_DIR")
+	for _, dir := range report.IniScanDirs {
+		logger.Subprocess("%s", dir)
+	}
+	logger.Break()
+
+	logger.Process("Rendered php-redis.ini")
+	for _, line := range strings.Split(strings.TrimSpace(report.RenderedIni), "\n") {
+		logger.Subprocess("%s", line)
+	}
+	logger.Break()
+
+	if len(report.Warnings) > 0 {
+		logger.Process("Warnings")
+		for _, warning := range report.Warnings {
+			logger.Subprocess("%s", warning)
+		}
+	}
+
+	return nil
+}
+
+func settingValue(config phpredishandler.RedisConfig, name string) string {
+	switch name {
+	case "host":
+		return config.Hostname
+	case "port":
+		return fmt.Sprint(config.Port)
+	case "password":
+		if config.Password == "" {
+			return "(none)"
+		}
+		return "REDACTED"
+	case "prefix":
+		return config.SessionKeyPrefix()
+	case "client":
+		client, err := phpredishandler.LookupSessionClient(config.Client)
+		if err != nil {
+			return config.Client
+		}
+		return client.Name
+	case "locking_enabled":
+		return fmt.Sprint(config.LockingEnabled)
+	case "lock_expire":
+		return phpredisDefault(config.LockExpire)
+	case "lock_wait_time":
+		return phpredisDefault(config.LockWaitTime)
+	case "lock_retries":
+		return phpredisDefault(config.LockRetries)
+	case "gc_maxlifetime":
+		return phpDefault(config.GcMaxLifetime)
+	case "lazy_write":
+		return phpDefaultBool(config.LazyWrite)
+	case "early_refresh":
+		return fmt.Sprint(config.EarlyRefresh)
+	case "serializer":
+		return phpDefaultString(config.Serializer)
+	case "compression":
+		if config.Compression == "" {
+			return "(none)"
+		}
+		return config.Compression
+	case "compression_level":
+		return phpredisDefault(config.CompressionLevel)
+	case "session_name":
+		return config.SessionCookieName()
+	case "cookie_domain":
+		return phpDefaultString(config.CookieDomain)
+	case "cookie_path":
+		return phpDefaultString(config.CookiePath)
+	case "cookie_secure":
+		return phpDefaultBool(config.CookieSecure)
+	case "cookie_httponly":
+		return phpDefaultBool(config.CookieHttpOnly)
+	case "cookie_samesite":
+		return phpDefaultString(config.CookieSameSite)
+	case "use_strict_mode":
+		return phpDefaultBool(config.UseStrictMode)
+	case "sid_length":
+		return phpDefault(config.SidLength)
+	case "sid_bits_per_character":
+		return phpDefault(config.SidBitsPerCharacter)
+	case "hardened":
+		return fmt.Sprint(config.Hardened)
+	}
+
+	return ""
+}
+
+// phpDefault formats an optional integer setting, where zero keeps the PHP
+// default.
+func phpDefault(value int) string {
+	if value == 0 {
+		return "(PHP default)"
+	}
+
+	return fmt.Sprint(value)
+}
+
+// phpDefaultString formats an optional string setting, where an empty value
+// keeps the PHP default.
+func phpDefaultString(value string) string {
+	if value == "" {
+		return "(PHP default)"
+	}
+
+	return value
+}
+
+// phpDefaultBool formats an optional boolean setting, where nil keeps the PHP
+// default.
+func phpDefaultBool(value *bool) string {
+	if value == nil {
+		return "(PHP default)"
+	}
+
+	return fmt.Sprint(*value)
+}
+
+// phpredisDefault formats an optional integer setting, where zero keeps the
+// phpredis default.
+func phpredisDefault(value int) string {
+	if value == 0 {
+		return "(phpredis default)"
+	}
+
+	return fmt.Sprint(value)
+}
+
+func render(args []string, _ scribe.Emitter) error {
+	cnbPathDefault := os.Getenv("CNB_BUILDPACK_DIR")
+	if cnbPathDefault == "" {
+		cnbPathDefault = "."
+	}
+
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	bindingPath := flags.String("binding", "", "path to a php-redis-session binding (defaults to resolving it from SERVICE_BINDING_ROOT)")
+	cnbPath := flags.String("cnb-path", cnbPathDefault, "buildpack directory containing config/php-redis.ini")
+	workingDir := flags.String("working-dir", ".", "application directory that may contain "+phpredishandler.CustomRedisIniTemplate)
+	showSecrets := flags.Bool("show-secrets", false, "print the password instead of redacting it")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	redisConfig, err := loadRedisConfig(*bindingPath)
+	if err != nil {
+		return err
+	}
+
+	// The build environment variables take precedence over the binding, as
+	// they do during the build.
+	redisConfig, err = redisConfig.ApplyEnvironment(phpredishandler.LoadEnvironment(os.Environ()))
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "php-redis-render")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	client, err := phpredishandler.LookupSessionClient(redisConfig.Client)
+	if err != nil {
+		return err
+	}
+
+	// Without a PHP installation to inspect, every extension is loaded.
+	var extensions []string
+	for _, name := range redisConfig.Extensions(client) {
+		extensions = append(extensions, name+".so")
+	}
+
+	templatePath, err := phpredishandler.RedisIniTemplate(*workingDir, *cnbPath)
+	if err != nil {
+		return err
+	}
+
+	path, err := phpredishandler.NewRedisConfigWriter(scribe.NewEmitter(io.Discard)).Write(redisConfig, extensions, dir, templatePath)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if !*showSecrets {
+		content = []byte(phpredishandler.RedactSecrets(string(content)))
+	}
+
+	_, err = os.Stdout.Write(content)
+	return err
+}
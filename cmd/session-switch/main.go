@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+)
+
+// session-switch is an exec.d helper that disables the session
+// configuration of the php-redis-config layer at launch when
+// BPL_PHP_REDIS_SESSION_ENABLED is false, and loads the layer after all other
+// ini directories when BPL_PHP_REDIS_SESSION_INI_LAST is true.
+func main() {
+	err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "session-switch: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	// The helper is installed into <layer>/exec.d.
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	layerPath := filepath.Dir(filepath.Dir(executable))
+
+	environment := phpredishandler.LoadEnvironment(os.Environ())
+	env, err := phpredishandler.SessionSwitch(environment, layerPath)
+	if err != nil {
+		return err
+	}
+
+	if len(env) == 0 {
+		return nil
+	}
+
+	disabled, err := phpredishandler.SessionsDisabled(environment)
+	if err != nil {
+		// not tested
+		return err
+	}
+
+	if disabled {
+		warnings := phpredishandler.SessionSwitchWarnings(environment, layerPath)
+		if len(warnings) == 0 {
+			fmt.Fprintf(os.Stderr, "session-switch: %s is false: sessions use the files handler instead of the configured session backend\n", phpredishandler.SessionEnabledEnv)
+		}
+
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "session-switch: warning: %s is false, but %s\n", phpredishandler.SessionEnabledEnv, warning)
+		}
+	}
+
+	// exec.d helpers report the environment variables to set as TOML on
+	// file descriptor 3.
+	output := os.NewFile(3, "/dev/fd/3")
+	defer func() {
+		_ = output.Close()
+	}()
+
+	var names []string
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		_, err = fmt.Fprintf(output, "%s = %s\n", name, strconv.Quote(env[name]))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+<?php
+// Registers a Redis session handler backed by Predis. Generated by the
+// php-redis-session-handler buildpack; do not edit.
+
+if (!class_exists('PaketoPredisSessionHandler', false)) {
+    require_once {{php .Autoload}};
+
+    final class PaketoPredisSessionHandler implements SessionHandlerInterface
+    {
+        private $client;
+
+        public function __construct(Predis\ClientInterface $client)
+        {
+            $this->client = $client;
+        }
+
+        #[\ReturnTypeWillChange]
+        public function open($path, $name)
+        {
+            return true;
+        }
+
+        #[\ReturnTypeWillChange]
+        public function close()
+        {
+            return true;
+        }
+
+        #[\ReturnTypeWillChange]
+        public function read($id)
+        {
+            $data = $this->client->get($id);
+
+            return $data === null ? '' : $data;
+        }
+
+        #[\ReturnTypeWillChange]
+        public function write($id, $data)
+        {
+            $this->client->setex($id, max(1, (int) ini_get('session.gc_maxlifetime')), $data);
+
+            return true;
+        }
+
+        #[\ReturnTypeWillChange]
+        public function destroy($id)
+        {
+            $this->client->del([$id]);
+
+            return true;
+        }
+
+        #[\ReturnTypeWillChange]
+        public function gc($max_lifetime)
+        {
+            // Sessions expire through their TTL.
+            return 0;
+        }
+    }
+
+    if (session_status() === PHP_SESSION_NONE) {
+        session_set_save_handler(new PaketoPredisSessionHandler(new Predis\Client(
+            {{php .Parameters}},
+            ['prefix' => {{php .Prefix}}]
+        )), true);
+    }
+}
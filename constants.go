@@ -4,4 +4,110 @@ const (
 	PhpLayer         = "php"
 	PhpRedisLayer    = "php-redis-config"
 	RedisBindingType = "php-redis-session"
+
+	MemcachedBindingType = "php-memcached-session"
+
+	// BindingTypesEnv lists the binding types that configure the Redis
+	// session backend, replacing DefaultRedisBindingTypes.
+	BindingTypesEnv = "BP_PHP_REDIS_SESSION_BINDING_TYPES"
+
+	// SourceDefault marks a setting in RedisConfig.Sources that was not
+	// configured and uses its default value.
+	SourceDefault = "default"
+
+	// DefaultSessionPrefix is the key prefix phpredis uses for sessions when
+	// none is configured.
+	DefaultSessionPrefix = "PHPREDIS_SESSION:"
+
+	// DefaultSessionName is the session.name PHP uses when none is
+	// configured.
+	DefaultSessionName = "PHPSESSID"
+
+	// CustomRedisIniTemplate is the path, relative to the application's
+	// working directory, of a php-redis.ini template that replaces the one
+	// shipped in the buildpack.
+	CustomRedisIniTemplate = ".php-redis-session/php-redis.ini.tmpl"
+
+	// IniConflictsEnv selects what the build does when other ini files set
+	// the session directives of this buildpack to different values: one of
+	// IniConflictsWarn, IniConflictsFail or IniConflictsOverride.
+	IniConflictsEnv = "BP_PHP_REDIS_SESSION_INI_CONFLICTS"
+
+	// IniLoadLastEnv makes the SessionSwitchExecD helper move the directories
+	// of the layer to the end of PHP_INI_SCAN_DIR at launch. The build sets it
+	// by default with the IniConflictsOverride policy.
+	IniLoadLastEnv = "BPL_PHP_REDIS_SESSION_INI_LAST"
+
+	// SessionScopeEnv selects which processes load the session directives:
+	// SessionScopeAll or SessionScopeWeb. In the web scope, the process types
+	// listed in WebProcessesEnv load them from WebIniDir in the layer.
+	SessionScopeEnv = "BP_PHP_REDIS_SESSION_SCOPE"
+	WebProcessesEnv = "BP_PHP_REDIS_SESSION_WEB_PROCESSES"
+	WebIniDir       = "web"
+
+	// ExtensionsIniFile loads the extensions in the layer when the session
+	// directives are scoped to web processes.
+	ExtensionsIniFile = "php-redis-extensions.ini"
+
+	// FpmAdminEnv enables a php-fpm pool configuration that locks the
+	// session handler with php_admin_value in the pool named by FpmPoolEnv,
+	// DefaultFpmPool unless set. It is written to FpmPoolFile in the layer
+	// and included from FpmPoolFile in FpmPoolDir, which the php-fpm buildpack
+	// includes into its configuration, named by FpmConfigEnv.
+	FpmAdminEnv     = "BP_PHP_REDIS_SESSION_FPM_ADMIN"
+	FpmPoolEnv      = "BP_PHP_REDIS_SESSION_FPM_POOL"
+	DefaultFpmPool  = "www"
+	FpmPoolDir      = ".php.fpm.d"
+	FpmPoolFile     = "php-redis-session.conf"
+	FpmPoolTemplate = "php-fpm-pool.conf"
+	FpmConfigEnv    = "PHP_FPM_PATH"
+
+	// SettingsPrefix starts the names of the environment variables of the
+	// session settings. ProcessesEnv lists the process types that get their
+	// own session configuration in ProcessIniDir, with the settings of a
+	// process type read from variables starting with ProcessSettingsPrefix.
+	SettingsPrefix        = "BP_PHP_REDIS_SESSION_"
+	ProcessesEnv          = "BP_PHP_REDIS_SESSION_PROCESSES"
+	ProcessSettingsPrefix = "BP_PHP_REDIS_SESSION_PROCESS_"
+	ProcessIniDir         = "processes"
+
+	// SessionEnabledEnv disables the session configuration at launch when it
+	// is false. The SessionSwitchExecD helper then loads the ini file in
+	// DisabledIniDir after all others.
+	SessionEnabledEnv  = "BPL_PHP_REDIS_SESSION_ENABLED"
+	SessionSwitchExecD = "session-switch"
+	DisabledIniDir     = "disabled"
+
+	// BuildSessionEnv makes the session configuration available to the
+	// build as well, for example to composer scripts.
+	BuildSessionEnv = "BP_PHP_REDIS_SESSION_BUILD"
+
+	// ExportEnv publishes the connection details of the Redis session backend
+	// as launch environment variables for application code. ExportPrefixEnv
+	// and ExportNamesEnv select their names.
+	ExportEnv       = "BP_PHP_REDIS_SESSION_EXPORT_ENV"
+	ExportPrefixEnv = "BP_PHP_REDIS_SESSION_EXPORT_PREFIX"
+	ExportNamesEnv  = "BP_PHP_REDIS_SESSION_EXPORT_NAMES"
+
+	// FrameworkEnv selects the framework integration: FrameworkAuto, the
+	// default, FrameworkNone or the name of a framework.
+	FrameworkEnv = "BP_PHP_REDIS_SESSION_FRAMEWORK"
+
+	// SessionToolName is the name of the companion CLI that is installed into
+	// the php-redis-config layer.
+	SessionToolName = "php-redis-session"
+
+	MigrateProcessType  = "redis-session-migrate"
+	ExporterProcessType = "redis-session-exporter"
+
+	// SessionClientEnv selects the session client at build time, overriding
+	// the client set in the binding.
+	SessionClientEnv = "BP_PHP_REDIS_SESSION_CLIENT"
+
+	// PredisVendorDir is where Composer installs predis/predis, the pure-PHP
+	// Redis client used by the predis session client, in the application.
+	// PredisHandlerFile is the auto_prepend_file that registers its session
+	// handler.
+	PredisVendorDir   = "vendor/predis/predis"
+	PredisHandlerFile = "predis-session-handler.php"
 )
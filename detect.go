@@ -6,23 +6,34 @@ import (
 )
 
 //go:generate faux --interface DetectBindingResolver --output fakes/detect_binding_resolver.go
+
+// BuildPlanMetadata is the metadata of the php requirement. The keys are
+// lower case, as the buildpacks that provide php read them.
 type BuildPlanMetadata struct {
-	Launch bool
+	Build  bool `toml:"build"`
+	Launch bool `toml:"launch"`
 }
 
 type DetectBindingResolver interface {
 	Resolve(typ, provider, platformDir string) ([]servicebindings.Binding, error)
 }
 
-func Detect(bindingResolver DetectBindingResolver) packit.DetectFunc {
+func Detect(bindingResolver DetectBindingResolver, environment Environment) packit.DetectFunc {
 	return func(context packit.DetectContext) (packit.DetectResult, error) {
-		redisBindings, err := bindingResolver.Resolve(RedisBindingType, "", context.Platform.Path)
-		if err != nil {
-			return packit.DetectResult{}, err
+		types := append(append([]string{}, RedisBindingTypes(environment)...), MemcachedBindingType)
+
+		var bindings []servicebindings.Binding
+		for _, typ := range types {
+			typeBindings, err := bindingResolver.Resolve(typ, "", context.Platform.Path)
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			bindings = append(bindings, typeBindings...)
 		}
 
-		if len(redisBindings) < 1 {
-			return packit.DetectResult{}, packit.Fail.WithMessage("no service bindings of type `" + RedisBindingType + "` provided")
+		if len(bindings) < 1 {
+			return packit.DetectResult{}, packit.Fail.WithMessage("no service bindings of type %s provided", describeBindingTypes(types))
 		}
 
 		return packit.DetectResult{
@@ -31,6 +42,7 @@ func Detect(bindingResolver DetectBindingResolver) packit.DetectFunc {
 					{
 						Name: "php",
 						Metadata: BuildPlanMetadata{
+							Build:  true,
 							Launch: true,
 						},
 					},
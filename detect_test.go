@@ -1,9 +1,11 @@
 package phpredishandler_test
 
 import (
+	"bytes"
 	"errors"
 	"testing"
 
+	"github.com/BurntSushi/toml"
 	. "github.com/onsi/gomega"
 	"github.com/paketo-buildpacks/packit/v2"
 	"github.com/paketo-buildpacks/packit/v2/servicebindings"
@@ -17,20 +19,38 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 		Expect = NewWithT(t).Expect
 
 		detectBindingResolver *fakes.DetectBindingResolver
+		bindings              map[string][]servicebindings.Binding
+		resolvedTypes         []string
+		environment           phpredishandler.Environment
 		detect                packit.DetectFunc
 	)
 
 	it.Before(func() {
-		detectBindingResolver = &fakes.DetectBindingResolver{}
-		detectBindingResolver.ResolveCall.Returns.BindingSlice = []servicebindings.Binding{
-			{
-				Type: "php-redis-session",
+		bindings = map[string][]servicebindings.Binding{
+			"php-redis-session": {
+				{
+					Type: "php-redis-session",
+				},
 			},
 		}
-		detect = phpredishandler.Detect(detectBindingResolver)
+		resolvedTypes = nil
+
+		detectBindingResolver = &fakes.DetectBindingResolver{}
+		detectBindingResolver.ResolveCall.Stub = func(typ, provider, platformDir string) ([]servicebindings.Binding, error) {
+			resolvedTypes = append(resolvedTypes, typ)
+			return bindings[typ], nil
+		}
+		environment = phpredishandler.Environment{}
+		detect = phpredishandler.Detect(detectBindingResolver, environment)
 	})
 
-	it("requires php during launch and provides nothing", func() {
+	it("writes the php requirement metadata with the keys the php buildpacks read", func() {
+		buffer := bytes.NewBuffer(nil)
+		Expect(toml.NewEncoder(buffer).Encode(phpredishandler.BuildPlanMetadata{Build: true, Launch: true})).To(Succeed())
+		Expect(buffer.String()).To(Equal("build = true\nlaunch = true\n"))
+	})
+
+	it("requires php during build and launch and provides nothing", func() {
 		result, err := detect(packit.DetectContext{
 			Platform: packit.Platform{
 				Path: "some-platform-path",
@@ -43,6 +63,7 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 				{
 					Name: "php",
 					Metadata: phpredishandler.BuildPlanMetadata{
+						Build:  true,
 						Launch: true,
 					},
 				},
@@ -50,14 +71,86 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 			Provides: []packit.BuildPlanProvision{},
 		}))
 
-		Expect(detectBindingResolver.ResolveCall.Receives.Typ).To(Equal("php-redis-session"))
+		Expect(resolvedTypes).To(Equal([]string{
+			"php-redis-session",
+			"php-valkey-session",
+			"valkey",
+			"php-keydb-session",
+			"keydb",
+			"php-dragonfly-session",
+			"dragonfly",
+			"php-memcached-session",
+		}))
 		Expect(detectBindingResolver.ResolveCall.Receives.Provider).To(Equal(""))
 		Expect(detectBindingResolver.ResolveCall.Receives.PlatformDir).To(Equal("some-platform-path"))
 	})
 
-	context("there are no php-redis-session bindings provided", func() {
+	context("there is a php-memcached-session binding instead", func() {
+		it.Before(func() {
+			bindings = map[string][]servicebindings.Binding{
+				"php-memcached-session": {
+					{
+						Type: "php-memcached-session",
+					},
+				},
+			}
+		})
+
+		it("requires php during build and launch", func() {
+			result, err := detect(packit.DetectContext{
+				Platform: packit.Platform{
+					Path: "some-platform-path",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires).To(HaveLen(1))
+			Expect(result.Plan.Requires[0].Name).To(Equal("php"))
+		})
+	})
+
+	context("there is a valkey binding instead", func() {
+		it.Before(func() {
+			bindings = map[string][]servicebindings.Binding{
+				"valkey": {
+					{
+						Type: "valkey",
+					},
+				},
+			}
+		})
+
+		it("requires php during build and launch", func() {
+			result, err := detect(packit.DetectContext{
+				Platform: packit.Platform{
+					Path: "some-platform-path",
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Requires).To(HaveLen(1))
+			Expect(result.Plan.Requires[0].Name).To(Equal("php"))
+		})
+	})
+
+	context("when BP_PHP_REDIS_SESSION_BINDING_TYPES is set", func() {
+		it.Before(func() {
+			environment["BP_PHP_REDIS_SESSION_BINDING_TYPES"] = "some-session-type"
+			bindings = map[string][]servicebindings.Binding{}
+		})
+
+		it("only resolves the configured types and memcached", func() {
+			_, err := detect(packit.DetectContext{
+				Platform: packit.Platform{
+					Path: "some-platform-path",
+				},
+			})
+			Expect(err).To(MatchError(packit.Fail.WithMessage("no service bindings of type `some-session-type` or `php-memcached-session` provided")))
+			Expect(resolvedTypes).To(Equal([]string{"some-session-type", "php-memcached-session"}))
+		})
+	})
+
+	context("there are no session bindings provided", func() {
 		it.Before(func() {
-			detectBindingResolver.ResolveCall.Returns.BindingSlice = []servicebindings.Binding{}
+			bindings = map[string][]servicebindings.Binding{}
 		})
 
 		it("detection fails", func() {
@@ -66,13 +159,14 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 					Path: "some-platform-path",
 				},
 			})
-			Expect(err).To(MatchError(packit.Fail.WithMessage("no service bindings of type `php-redis-session` provided")))
+			Expect(err).To(MatchError(packit.Fail.WithMessage("no service bindings of type `php-redis-session`, `php-valkey-session`, `valkey`, `php-keydb-session`, `keydb`, `php-dragonfly-session`, `dragonfly` or `php-memcached-session` provided")))
 		})
 	})
 
 	context("failure cases", func() {
 		context("the binding resolver fails to resolve bindings", func() {
 			it.Before(func() {
+				detectBindingResolver.ResolveCall.Stub = nil
 				detectBindingResolver.ResolveCall.Returns.Error = errors.New("failed to resolve bindings")
 			})
 
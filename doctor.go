@@ -0,0 +1,254 @@
+package phpredishandler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/fs"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+)
+
+type ExtensionCheck struct {
+	Name  string
+	Path  string
+	Found bool
+}
+
+// DoctorReport explains how the session configuration was resolved.
+type DoctorReport struct {
+	Detected      bool
+	DetectMessage string
+
+	Bindings        []servicebindings.Binding
+	SelectedBinding string
+	Config          RedisConfig
+
+	Extensions  []ExtensionCheck
+	IniScanDirs []string
+	RenderedIni string
+
+	Warnings []string
+}
+
+func (r *DoctorReport) warn(format string, v ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, v...))
+}
+
+type Doctor struct {
+	detectBindingResolver DetectBindingResolver
+	buildBindingResolver  BuildBindingResolver
+	configParser          ConfigParser
+	configWriter          ConfigWriter
+	environment           Environment
+}
+
+func NewDoctor(detectBindingResolver DetectBindingResolver, buildBindingResolver BuildBindingResolver, configParser ConfigParser, configWriter ConfigWriter, environment Environment) Doctor {
+	return Doctor{
+		detectBindingResolver: detectBindingResolver,
+		buildBindingResolver:  buildBindingResolver,
+		configParser:          configParser,
+		configWriter:          configWriter,
+		environment:           environment,
+	}
+}
+
+// Diagnose resolves the session configuration the same way the detect and
+// build phases do and reports every decision along the way. Problems are
+// recorded as warnings so that a single run surfaces all of them.
+//
+// The templatePath is the php-redis.ini template rendered from the current
+// binding, as returned by RedisIniTemplate. When it is empty, the php-redis.ini
+// rendered at build time in layerPath is reported instead.
+func (d Doctor) Diagnose(platformPath, templatePath, layerPath string) DoctorReport {
+	var report DoctorReport
+
+	_, err := Detect(d.detectBindingResolver, d.environment)(packit.DetectContext{
+		Platform: packit.Platform{Path: platformPath},
+	})
+	report.Detected = err == nil
+	if err != nil {
+		report.DetectMessage = err.Error()
+	}
+
+	for _, typ := range RedisBindingTypes(d.environment) {
+		bindings, err := d.detectBindingResolver.Resolve(typ, "", platformPath)
+		if err != nil {
+			report.warn("failed to list %s bindings: %s", typ, err)
+		}
+		report.Bindings = append(report.Bindings, bindings...)
+	}
+
+	configured := d.resolveConfig(&report, platformPath)
+
+	d.checkExtensions(&report)
+	d.checkIniScanDir(&report, layerPath)
+
+	if configured && templatePath != "" {
+		report.RenderedIni, err = d.render(report.Config, templatePath)
+		if err != nil {
+			report.warn("failed to render php-redis.ini: %s", err)
+		}
+	} else if layerPath != "" {
+		path := filepath.Join(layerPath, "php-redis.ini")
+		if exists, _ := fs.Exists(filepath.Join(layerPath, WebIniDir)); exists {
+			path = filepath.Join(layerPath, WebIniDir, "php-redis.ini")
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			report.warn("failed to read the rendered php-redis.ini: %s", err)
+		} else {
+			report.RenderedIni = RedactSecrets(string(content))
+		}
+	}
+
+	return report
+}
+
+func (d Doctor) resolveConfig(report *DoctorReport, platformPath string) bool {
+	bindingType, err := selectBindingType(d.buildBindingResolver, RedisBindingTypes(d.environment), platformPath)
+	if err != nil {
+		report.warn("failed to select a session binding: %s", err)
+		return false
+	}
+
+	if bindingType == MemcachedBindingType {
+		report.warn("the %s binding is not diagnosed: only Redis bindings are supported", bindingType)
+		return false
+	}
+
+	binding, err := d.buildBindingResolver.ResolveOne(bindingType, "", platformPath)
+	if err != nil {
+		report.warn("failed to select a %s binding: %s", bindingType, err)
+		return false
+	}
+	report.SelectedBinding = binding.Path
+
+	report.Config, err = d.configParser.Parse(binding.Path)
+	if err != nil {
+		report.warn("failed to parse the %s binding: %s", bindingType, err)
+		return false
+	}
+
+	return d.applyEnvironment(report)
+}
+
+// applyEnvironment applies the settings selected at build time, which take
+// precedence over the binding.
+func (d Doctor) applyEnvironment(report *DoctorReport) bool {
+	config, err := report.Config.ApplyEnvironment(d.environment)
+	if err != nil {
+		report.warn("%s", err)
+		return false
+	}
+
+	report.Config = config
+	report.Warnings = append(report.Warnings, config.Warnings()...)
+	return true
+}
+
+func (d Doctor) checkExtensions(report *DoctorReport) {
+	extensionDir, _ := d.environment.Lookup("PHP_EXTENSION_DIR")
+	if extensionDir == "" {
+		report.warn("PHP_EXTENSION_DIR is not set: cannot locate the extensions of the PHP layer")
+		return
+	}
+
+	client, err := LookupSessionClient(report.Config.Client)
+	if err != nil {
+		report.warn("%s", err)
+		return
+	}
+
+	for _, name := range report.Config.Extensions(client) {
+		check := ExtensionCheck{
+			Name: name,
+			Path: filepath.Join(extensionDir, name+".so"),
+		}
+
+		found, err := fs.Exists(check.Path)
+		if err != nil {
+			report.warn("failed to check for %s: %s", check.Path, err)
+		}
+		check.Found = found
+
+		if !found {
+			report.warn("%s.so was not found in %s", name, extensionDir)
+		}
+
+		report.Extensions = append(report.Extensions, check)
+	}
+}
+
+func (d Doctor) checkIniScanDir(report *DoctorReport, layerPath string) {
+	scanDir, _ := d.environment.Lookup("PHP_INI_SCAN_DIR")
+	if scanDir == "" {
+		report.warn("PHP_INI_SCAN_DIR is not set: php-redis.ini will not be loaded")
+		return
+	}
+
+	dirs := strings.Split(scanDir, string(os.PathListSeparator))
+	report.IniScanDirs = dirs
+
+	position := -1
+	for i, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+
+		exists, err := fs.Exists(dir)
+		if err != nil || !exists {
+			report.warn("PHP_INI_SCAN_DIR entry %s does not exist", dir)
+		}
+
+		// The session directives can also be loaded from directories inside
+		// the layer that follow the layer itself, such as WebIniDir.
+		if layerPath != "" && (filepath.Clean(dir) == filepath.Clean(layerPath) || strings.HasPrefix(filepath.Clean(dir), filepath.Clean(layerPath)+string(filepath.Separator))) {
+			position = i
+		}
+	}
+
+	if layerPath != "" {
+		switch {
+		case position < 0:
+			report.warn("PHP_INI_SCAN_DIR does not include %s: php-redis.ini will not be loaded", layerPath)
+		case position < len(dirs)-1:
+			report.warn("PHP_INI_SCAN_DIR lists directories after %s that can override its session settings: %s", layerPath, strings.Join(dirs[position+1:], ", "))
+		}
+	}
+}
+
+func (d Doctor) render(config RedisConfig, templatePath string) (string, error) {
+	dir, err := os.MkdirTemp("", "php-redis-doctor")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	client, err := LookupSessionClient(config.Client)
+	if err != nil {
+		return "", err
+	}
+
+	var extensions []string
+	for _, name := range config.Extensions(client) {
+		extensions = append(extensions, name+".so")
+	}
+
+	path, err := d.configWriter.Write(config, extensions, dir, templatePath)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return RedactSecrets(string(content)), nil
+}
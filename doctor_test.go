@@ -0,0 +1,211 @@
+package phpredishandler_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/paketo-buildpacks/php-redis-session-handler/fakes"
+	"github.com/sclevine/spec"
+)
+
+func testDoctor(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		layerPath    string
+		extensionDir string
+
+		detectBindingResolver *fakes.DetectBindingResolver
+		buildBindingResolver  *fakes.BuildBindingResolver
+		configParser          *fakes.ConfigParser
+		configWriter          *fakes.ConfigWriter
+		environment           phpredishandler.Environment
+
+		doctor phpredishandler.Doctor
+	)
+
+	it.Before(func() {
+		layerPath = t.TempDir()
+		extensionDir = t.TempDir()
+
+		Expect(os.WriteFile(filepath.Join(extensionDir, "redis.so"), nil, 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(extensionDir, "igbinary.so"), nil, 0600)).To(Succeed())
+
+		binding := servicebindings.Binding{
+			Name: "some-binding",
+			Type: "php-redis-session",
+			Path: "some-binding-path",
+		}
+
+		detectBindingResolver = &fakes.DetectBindingResolver{}
+		detectBindingResolver.ResolveCall.Stub = func(typ, provider, platformDir string) ([]servicebindings.Binding, error) {
+			if typ == binding.Type {
+				return []servicebindings.Binding{binding}, nil
+			}
+			return nil, nil
+		}
+
+		buildBindingResolver = &fakes.BuildBindingResolver{}
+		buildBindingResolver.ResolveOneCall.Returns.Binding = binding
+
+		configParser = &fakes.ConfigParser{}
+		configParser.ParseCall.Returns.RedisConfig = phpredishandler.RedisConfig{
+			Hostname: "some-host",
+			Port:     1234,
+			Password: "some-password",
+			Sources: map[string]string{
+				"host":     "some-binding-path/host",
+				"port":     "some-binding-path/port",
+				"password": "some-binding-path/password",
+				"prefix":   "default",
+			},
+		}
+
+		configWriter = &fakes.ConfigWriter{}
+		configWriter.WriteCall.Stub = func(config phpredishandler.RedisConfig, extensions []string, layerPath, cnbPath string) (string, error) {
+			path := filepath.Join(layerPath, "php-redis.ini")
+			return path, os.WriteFile(path, []byte(`session.save_path="tcp://some-host:1234?auth=some-password"`), 0600)
+		}
+
+		environment = phpredishandler.Environment{
+			"PHP_EXTENSION_DIR": extensionDir,
+			"PHP_INI_SCAN_DIR":  extensionDir + string(os.PathListSeparator) + layerPath,
+		}
+
+		doctor = phpredishandler.NewDoctor(detectBindingResolver, buildBindingResolver, configParser, configWriter, environment)
+	})
+
+	it("reports the resolved configuration without warnings", func() {
+		report := doctor.Diagnose("some-platform-path", "some-template-path", layerPath)
+
+		Expect(report.Detected).To(BeTrue())
+		Expect(report.Bindings).To(HaveLen(1))
+		Expect(report.SelectedBinding).To(Equal("some-binding-path"))
+		Expect(report.Config.Hostname).To(Equal("some-host"))
+		Expect(report.Config.Sources).To(HaveKeyWithValue("port", "some-binding-path/port"))
+		Expect(report.Extensions).To(Equal([]phpredishandler.ExtensionCheck{
+			{Name: "redis", Path: filepath.Join(extensionDir, "redis.so"), Found: true},
+		}))
+		Expect(report.IniScanDirs).To(Equal([]string{extensionDir, layerPath}))
+		Expect(report.RenderedIni).To(Equal(`session.save_path="tcp://some-host:1234?auth=REDACTED"`))
+		Expect(report.Warnings).To(BeEmpty())
+
+		Expect(buildBindingResolver.ResolveOneCall.Receives.PlatformDir).To(Equal("some-platform-path"))
+		Expect(configParser.ParseCall.Receives.Dir).To(Equal("some-binding-path"))
+		Expect(configWriter.WriteCall.Receives.Extensions).To(Equal([]string{"redis.so"}))
+		Expect(configWriter.WriteCall.Receives.TemplatePath).To(Equal("some-template-path"))
+	})
+
+	context("when no cnb path is given", func() {
+		it.Before(func() {
+			err := os.WriteFile(filepath.Join(layerPath, "php-redis.ini"), []byte(`session.save_path="tcp://other-host:1234?auth=other-password"`), 0600)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("reports the php-redis.ini rendered at build time", func() {
+			report := doctor.Diagnose("some-platform-path", "", layerPath)
+
+			Expect(report.RenderedIni).To(Equal(`session.save_path="tcp://other-host:1234?auth=REDACTED"`))
+			Expect(report.Warnings).To(BeEmpty())
+		})
+	})
+
+	context("when BP_PHP_REDIS_SESSION_CLIENT selects relay", func() {
+		it.Before(func() {
+			environment["BP_PHP_REDIS_SESSION_CLIENT"] = "relay"
+			Expect(os.WriteFile(filepath.Join(extensionDir, "relay.so"), nil, 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(extensionDir, "msgpack.so"), nil, 0600)).To(Succeed())
+		})
+
+		it("reports the client and checks the relay extensions", func() {
+			report := doctor.Diagnose("some-platform-path", "some-template-path", layerPath)
+
+			Expect(report.Config.Client).To(Equal("relay"))
+			Expect(report.Config.Sources).To(HaveKeyWithValue("client", "BP_PHP_REDIS_SESSION_CLIENT"))
+			Expect(report.Extensions).To(HaveLen(3))
+			Expect(report.Extensions[0].Name).To(Equal("relay"))
+			Expect(configWriter.WriteCall.Receives.Extensions).To(Equal([]string{"relay.so", "igbinary.so", "msgpack.so"}))
+			Expect(report.Warnings).To(BeEmpty())
+		})
+	})
+
+	context("when no binding can be selected", func() {
+		it.Before(func() {
+			detectBindingResolver.ResolveCall.Stub = nil
+			buildBindingResolver.ResolveOneCall.Returns.Error = errors.New("no bindings")
+		})
+
+		it("reports the detect failure and the resolution error", func() {
+			report := doctor.Diagnose("some-platform-path", "some-template-path", layerPath)
+
+			Expect(report.Detected).To(BeFalse())
+			Expect(report.DetectMessage).To(HavePrefix("no service bindings of type `php-redis-session`, `php-valkey-session`"))
+			Expect(report.Warnings).To(ContainElement("failed to select a php-redis-session binding: no bindings"))
+			Expect(configWriter.WriteCall.CallCount).To(Equal(0))
+		})
+	})
+
+	context("when the extensions are missing", func() {
+		it.Before(func() {
+			Expect(os.Remove(filepath.Join(extensionDir, "redis.so"))).To(Succeed())
+		})
+
+		it("warns about each missing extension", func() {
+			report := doctor.Diagnose("some-platform-path", "some-template-path", layerPath)
+
+			Expect(report.Extensions[0].Found).To(BeFalse())
+			Expect(report.Warnings).To(Equal([]string{
+				"redis.so was not found in " + extensionDir,
+			}))
+		})
+	})
+
+	context("when the session configuration is scoped to web processes", func() {
+		it.Before(func() {
+			Expect(os.MkdirAll(filepath.Join(layerPath, "web"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(layerPath, "web", "php-redis.ini"), []byte(`session.save_path="tcp://web-host:1234?auth=web-password"`), 0600)).To(Succeed())
+			environment["PHP_INI_SCAN_DIR"] = extensionDir + string(os.PathListSeparator) + layerPath + string(os.PathListSeparator) + filepath.Join(layerPath, "web")
+		})
+
+		it("reports the php-redis.ini of the web processes", func() {
+			report := doctor.Diagnose("some-platform-path", "", layerPath)
+
+			Expect(report.RenderedIni).To(Equal(`session.save_path="tcp://web-host:1234?auth=REDACTED"`))
+			Expect(report.Warnings).To(BeEmpty())
+		})
+	})
+
+	context("when PHP_INI_SCAN_DIR lists directories after the layer", func() {
+		it.Before(func() {
+			environment["PHP_INI_SCAN_DIR"] = layerPath + string(os.PathListSeparator) + extensionDir
+		})
+
+		it("warns that they can override the session settings", func() {
+			report := doctor.Diagnose("some-platform-path", "some-template-path", layerPath)
+
+			Expect(report.Warnings).To(Equal([]string{
+				"PHP_INI_SCAN_DIR lists directories after " + layerPath + " that can override its session settings: " + extensionDir,
+			}))
+		})
+	})
+
+	context("when PHP_INI_SCAN_DIR does not include the layer", func() {
+		it.Before(func() {
+			environment["PHP_INI_SCAN_DIR"] = extensionDir + string(os.PathListSeparator) + "/no/such/dir"
+		})
+
+		it("warns that php-redis.ini will not be loaded", func() {
+			report := doctor.Diagnose("some-platform-path", "some-template-path", layerPath)
+
+			Expect(report.Warnings).To(Equal([]string{
+				"PHP_INI_SCAN_DIR entry /no/such/dir does not exist",
+				"PHP_INI_SCAN_DIR does not include " + layerPath + ": php-redis.ini will not be loaded",
+			}))
+		})
+	})
+}
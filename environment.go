@@ -0,0 +1,48 @@
+package phpredishandler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Environment holds the environment variables that configure the buildpack.
+// It is loaded once in main so that the detect and build phases can be tested
+// without modifying the process environment.
+type Environment map[string]string
+
+// LoadEnvironment builds an Environment from a list of KEY=value pairs, as
+// returned by os.Environ.
+func LoadEnvironment(environ []string) Environment {
+	environment := Environment{}
+	for _, variable := range environ {
+		name, value, found := strings.Cut(variable, "=")
+		if found {
+			environment[name] = value
+		}
+	}
+
+	return environment
+}
+
+// Lookup returns the value of the named variable and whether it is set.
+func (e Environment) Lookup(name string) (string, bool) {
+	value, ok := e[name]
+	return value, ok
+}
+
+// Bool parses the named variable as a boolean. Unset or empty variables are
+// false.
+func (e Environment) Bool(name string) (bool, error) {
+	value := e[name]
+	if value == "" {
+		return false, nil
+	}
+
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %s: %q is not a boolean", name, value)
+	}
+
+	return enabled, nil
+}
@@ -0,0 +1,47 @@
+package phpredishandler_test
+
+import (
+	"testing"
+
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testEnvironment(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	it("loads the environment from KEY=value pairs", func() {
+		environment := phpredishandler.LoadEnvironment([]string{"SOME_VAR=some=value", "EMPTY_VAR=", "MALFORMED"})
+		Expect(environment).To(Equal(phpredishandler.Environment{
+			"SOME_VAR":  "some=value",
+			"EMPTY_VAR": "",
+		}))
+
+		value, ok := environment.Lookup("SOME_VAR")
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal("some=value"))
+
+		_, ok = environment.Lookup("MALFORMED")
+		Expect(ok).To(BeFalse())
+	})
+
+	context("Bool", func() {
+		it("parses boolean values", func() {
+			environment := phpredishandler.Environment{"TRUE_VAR": "true", "FALSE_VAR": "0", "EMPTY_VAR": ""}
+
+			Expect(environment.Bool("TRUE_VAR")).To(BeTrue())
+			Expect(environment.Bool("FALSE_VAR")).To(BeFalse())
+			Expect(environment.Bool("EMPTY_VAR")).To(BeFalse())
+			Expect(environment.Bool("UNSET_VAR")).To(BeFalse())
+		})
+
+		context("when the value is not a boolean", func() {
+			it("returns an error", func() {
+				_, err := phpredishandler.Environment{"SOME_VAR": "maybe"}.Bool("SOME_VAR")
+				Expect(err).To(MatchError(`failed to parse SOME_VAR: "maybe" is not a boolean`))
+			})
+		})
+	})
+}
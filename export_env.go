@@ -0,0 +1,112 @@
+package phpredishandler
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ExportedDetails are the connection details that can be exported as launch
+// environment variables, by the names used in ExportNamesEnv.
+var ExportedDetails = []string{"host", "port", "password", "url"}
+
+// secretDetails are the exported details that contain the password.
+var secretDetails = []string{"password", "url"}
+
+// DefaultExportPrefix starts the names of the exported environment variables,
+// for example REDIS_HOST, unless ExportPrefixEnv is set.
+const DefaultExportPrefix = "REDIS_"
+
+// ExportNames returns the name of the environment variable of each exported
+// connection detail. The names are the ExportPrefixEnv followed by the upper
+// case detail, unless ExportNamesEnv lists detail=NAME pairs, separated by
+// commas or whitespace. A detail with an empty name is not exported.
+func ExportNames(environment Environment) (map[string]string, error) {
+	prefix, ok := environment.Lookup(ExportPrefixEnv)
+	if !ok {
+		prefix = DefaultExportPrefix
+	}
+
+	if prefix == "" {
+		return nil, fmt.Errorf("invalid %s: must not be empty", ExportPrefixEnv)
+	}
+
+	names := map[string]string{}
+	for _, detail := range ExportedDetails {
+		names[detail] = prefix + strings.ToUpper(detail)
+	}
+
+	value, _ := environment.Lookup(ExportNamesEnv)
+	for _, pair := range splitList(value) {
+		detail, name, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid %s entry %q: must be detail=NAME", ExportNamesEnv, pair)
+		}
+
+		if _, ok := names[detail]; !ok {
+			return nil, fmt.Errorf("invalid %s entry %q: the detail must be one of %s", ExportNamesEnv, pair, strings.Join(ExportedDetails, ", "))
+		}
+
+		names[detail] = name
+	}
+
+	return names, nil
+}
+
+// SecretExportNames returns the names of the exported environment variables
+// that contain the password, so that they can be redacted from logs.
+func SecretExportNames(names map[string]string) []string {
+	var secrets []string
+	for _, detail := range secretDetails {
+		if names[detail] != "" {
+			secrets = append(secrets, names[detail])
+		}
+	}
+
+	return secrets
+}
+
+// ExportedEnvironment returns the connection details of the config by the
+// given environment variable names. Details the config does not have, such
+// as an empty password, are left out.
+func (c RedisConfig) ExportedEnvironment(names map[string]string) map[string]string {
+	details := map[string]string{
+		"host":     c.Hostname,
+		"port":     strconv.Itoa(c.Port),
+		"password": c.Password,
+		"url":      c.URL(),
+	}
+
+	env := map[string]string{}
+	for detail, value := range details {
+		if names[detail] != "" && value != "" {
+			env[names[detail]] = value
+		}
+	}
+
+	return env
+}
+
+// URL returns the connection as a redis:// URL, or a rediss:// URL for TLS,
+// including the password. A database other than 0 is the path of the URL.
+func (c RedisConfig) URL() string {
+	u := url.URL{
+		Scheme: "redis",
+		Host:   net.JoinHostPort(c.Hostname, strconv.Itoa(c.Port)),
+	}
+	if c.TLS {
+		u.Scheme = "rediss"
+	}
+
+	if c.Database != 0 {
+		u.Path = "/" + strconv.Itoa(c.Database)
+	}
+
+	if c.Password != "" {
+		u.User = url.UserPassword("", c.Password)
+	}
+
+	return u.String()
+}
@@ -0,0 +1,105 @@
+package phpredishandler_test
+
+import (
+	"testing"
+
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testExportEnv(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		config phpredishandler.RedisConfig
+	)
+
+	it.Before(func() {
+		config = phpredishandler.RedisConfig{
+			Hostname: "some-host",
+			Port:     6379,
+			Password: "some-password",
+		}
+	})
+
+	context("ExportNames", func() {
+		it("prefixes the upper case details with REDIS_", func() {
+			names, err := phpredishandler.ExportNames(phpredishandler.Environment{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names).To(Equal(map[string]string{
+				"host":     "REDIS_HOST",
+				"port":     "REDIS_PORT",
+				"password": "REDIS_PASSWORD",
+				"url":      "REDIS_URL",
+			}))
+		})
+
+		it("uses the configured prefix and names", func() {
+			names, err := phpredishandler.ExportNames(phpredishandler.Environment{
+				"BP_PHP_REDIS_SESSION_EXPORT_PREFIX": "CACHE_",
+				"BP_PHP_REDIS_SESSION_EXPORT_NAMES":  "url=CACHE_DSN, password=",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names).To(Equal(map[string]string{
+				"host":     "CACHE_HOST",
+				"port":     "CACHE_PORT",
+				"password": "",
+				"url":      "CACHE_DSN",
+			}))
+			Expect(phpredishandler.SecretExportNames(names)).To(Equal([]string{"CACHE_DSN"}))
+		})
+
+		context("when the prefix is empty", func() {
+			it("returns an error", func() {
+				_, err := phpredishandler.ExportNames(phpredishandler.Environment{"BP_PHP_REDIS_SESSION_EXPORT_PREFIX": ""})
+				Expect(err).To(MatchError("invalid BP_PHP_REDIS_SESSION_EXPORT_PREFIX: must not be empty"))
+			})
+		})
+
+		context("when an entry is not a pair", func() {
+			it("returns an error", func() {
+				_, err := phpredishandler.ExportNames(phpredishandler.Environment{"BP_PHP_REDIS_SESSION_EXPORT_NAMES": "CACHE_HOST"})
+				Expect(err).To(MatchError(`invalid BP_PHP_REDIS_SESSION_EXPORT_NAMES entry "CACHE_HOST": must be detail=NAME`))
+			})
+		})
+
+		context("when an entry names an unknown detail", func() {
+			it("returns an error", func() {
+				_, err := phpredishandler.ExportNames(phpredishandler.Environment{"BP_PHP_REDIS_SESSION_EXPORT_NAMES": "database=REDIS_DB"})
+				Expect(err).To(MatchError(`invalid BP_PHP_REDIS_SESSION_EXPORT_NAMES entry "database=REDIS_DB": the detail must be one of host, port, password, url`))
+			})
+		})
+	})
+
+	context("ExportedEnvironment", func() {
+		var names map[string]string
+
+		it.Before(func() {
+			var err error
+			names, err = phpredishandler.ExportNames(phpredishandler.Environment{})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("exports the TCP connection", func() {
+			Expect(config.ExportedEnvironment(names)).To(Equal(map[string]string{
+				"REDIS_HOST":     "some-host",
+				"REDIS_PORT":     "6379",
+				"REDIS_PASSWORD": "some-password",
+				"REDIS_URL":      "redis://:some-password@some-host:6379",
+			}))
+		})
+
+		it("exports a rediss:// URL with the database for TLS", func() {
+			config.TLS = true
+			config.Database = 2
+			Expect(config.ExportedEnvironment(names)).To(HaveKeyWithValue("REDIS_URL", "rediss://:some-password@some-host:6379/2"))
+		})
+
+		it("leaves out the details without a name", func() {
+			names["password"] = ""
+			Expect(config.ExportedEnvironment(names)).NotTo(HaveKey("REDIS_PASSWORD"))
+		})
+	})
+}
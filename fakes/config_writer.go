@@ -8,10 +8,41 @@ import (
 
 type ConfigWriter struct {
 	WriteCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			RedisConfig  phpredishandler.RedisConfig
+			Extensions   []string
+			LayerPath    string
+			TemplatePath string
+		}
+		Returns struct {
+			String string
+			Error  error
+		}
+		Stub func(phpredishandler.RedisConfig, []string, string, string) (string, error)
+	}
+	WriteFpmPoolCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			RedisConfig phpredishandler.RedisConfig
+			Pool        string
+			Dir         string
+			CnbPath     string
+		}
+		Returns struct {
+			String string
+			Error  error
+		}
+		Stub func(phpredishandler.RedisConfig, string, string, string) (string, error)
+	}
+	WritePredisHandlerCall struct {
 		mutex     sync.Mutex
 		CallCount int
 		Receives  struct {
 			RedisConfig phpredishandler.RedisConfig
+			PredisPath  string
 			LayerPath   string
 			CnbPath     string
 		}
@@ -19,19 +50,48 @@ type ConfigWriter struct {
 			String string
 			Error  error
 		}
-		Stub func(phpredishandler.RedisConfig, string, string) (string, error)
+		Stub func(phpredishandler.RedisConfig, string, string, string) (string, error)
 	}
 }
 
-func (f *ConfigWriter) Write(param1 phpredishandler.RedisConfig, param2 string, param3 string) (string, error) {
+func (f *ConfigWriter) Write(param1 phpredishandler.RedisConfig, param2 []string, param3 string, param4 string) (string, error) {
 	f.WriteCall.mutex.Lock()
 	defer f.WriteCall.mutex.Unlock()
 	f.WriteCall.CallCount++
 	f.WriteCall.Receives.RedisConfig = param1
-	f.WriteCall.Receives.LayerPath = param2
-	f.WriteCall.Receives.CnbPath = param3
+	f.WriteCall.Receives.Extensions = param2
+	f.WriteCall.Receives.LayerPath = param3
+	f.WriteCall.Receives.TemplatePath = param4
 	if f.WriteCall.Stub != nil {
-		return f.WriteCall.Stub(param1, param2, param3)
+		return f.WriteCall.Stub(param1, param2, param3, param4)
 	}
 	return f.WriteCall.Returns.String, f.WriteCall.Returns.Error
 }
+
+func (f *ConfigWriter) WritePredisHandler(param1 phpredishandler.RedisConfig, param2 string, param3 string, param4 string) (string, error) {
+	f.WritePredisHandlerCall.mutex.Lock()
+	defer f.WritePredisHandlerCall.mutex.Unlock()
+	f.WritePredisHandlerCall.CallCount++
+	f.WritePredisHandlerCall.Receives.RedisConfig = param1
+	f.WritePredisHandlerCall.Receives.PredisPath = param2
+	f.WritePredisHandlerCall.Receives.LayerPath = param3
+	f.WritePredisHandlerCall.Receives.CnbPath = param4
+	if f.WritePredisHandlerCall.Stub != nil {
+		return f.WritePredisHandlerCall.Stub(param1, param2, param3, param4)
+	}
+	return f.WritePredisHandlerCall.Returns.String, f.WritePredisHandlerCall.Returns.Error
+}
+
+func (f *ConfigWriter) WriteFpmPool(param1 phpredishandler.RedisConfig, param2 string, param3 string, param4 string) (string, error) {
+	f.WriteFpmPoolCall.mutex.Lock()
+	defer f.WriteFpmPoolCall.mutex.Unlock()
+	f.WriteFpmPoolCall.CallCount++
+	f.WriteFpmPoolCall.Receives.RedisConfig = param1
+	f.WriteFpmPoolCall.Receives.Pool = param2
+	f.WriteFpmPoolCall.Receives.Dir = param3
+	f.WriteFpmPoolCall.Receives.CnbPath = param4
+	if f.WriteFpmPoolCall.Stub != nil {
+		return f.WriteFpmPoolCall.Stub(param1, param2, param3, param4)
+	}
+	return f.WriteFpmPoolCall.Returns.String, f.WriteFpmPoolCall.Returns.Error
+}
@@ -0,0 +1,91 @@
+package fakes
+
+import (
+	"sync"
+
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+)
+
+type ExtensionInspector struct {
+	InspectCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Returns   struct {
+			PhpInstallation phpredishandler.PhpInstallation
+			Error           error
+		}
+		Stub func() (phpredishandler.PhpInstallation, error)
+	}
+	IniFilesCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Returns   struct {
+			StringSlice []string
+			Error       error
+		}
+		Stub func() ([]string, error)
+	}
+	RedisCompressionsCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Extensions []string
+		}
+		Returns struct {
+			StringSlice []string
+			Error       error
+		}
+		Stub func([]string) ([]string, error)
+	}
+	RequiredExtensionsCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Extension string
+		}
+		Returns struct {
+			StringSlice []string
+			Error       error
+		}
+		Stub func(string) ([]string, error)
+	}
+}
+
+func (f *ExtensionInspector) Inspect() (phpredishandler.PhpInstallation, error) {
+	f.InspectCall.mutex.Lock()
+	defer f.InspectCall.mutex.Unlock()
+	f.InspectCall.CallCount++
+	if f.InspectCall.Stub != nil {
+		return f.InspectCall.Stub()
+	}
+	return f.InspectCall.Returns.PhpInstallation, f.InspectCall.Returns.Error
+}
+func (f *ExtensionInspector) IniFiles() ([]string, error) {
+	f.IniFilesCall.mutex.Lock()
+	defer f.IniFilesCall.mutex.Unlock()
+	f.IniFilesCall.CallCount++
+	if f.IniFilesCall.Stub != nil {
+		return f.IniFilesCall.Stub()
+	}
+	return f.IniFilesCall.Returns.StringSlice, f.IniFilesCall.Returns.Error
+}
+func (f *ExtensionInspector) RedisCompressions(param1 []string) ([]string, error) {
+	f.RedisCompressionsCall.mutex.Lock()
+	defer f.RedisCompressionsCall.mutex.Unlock()
+	f.RedisCompressionsCall.CallCount++
+	f.RedisCompressionsCall.Receives.Extensions = param1
+	if f.RedisCompressionsCall.Stub != nil {
+		return f.RedisCompressionsCall.Stub(param1)
+	}
+	return f.RedisCompressionsCall.Returns.StringSlice, f.RedisCompressionsCall.Returns.Error
+}
+func (f *ExtensionInspector) RequiredExtensions(param1 string) ([]string, error) {
+	f.RequiredExtensionsCall.mutex.Lock()
+	defer f.RequiredExtensionsCall.mutex.Unlock()
+	f.RequiredExtensionsCall.CallCount++
+	f.RequiredExtensionsCall.Receives.Extension = param1
+	if f.RequiredExtensionsCall.Stub != nil {
+		return f.RequiredExtensionsCall.Stub(param1)
+	}
+	return f.RequiredExtensionsCall.Returns.StringSlice, f.RequiredExtensionsCall.Returns.Error
+}
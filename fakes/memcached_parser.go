@@ -0,0 +1,33 @@
+package fakes
+
+import (
+	"sync"
+
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+)
+
+type MemcachedParser struct {
+	ParseCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Dir string
+		}
+		Returns struct {
+			MemcachedConfig phpredishandler.MemcachedConfig
+			Error           error
+		}
+		Stub func(string) (phpredishandler.MemcachedConfig, error)
+	}
+}
+
+func (f *MemcachedParser) Parse(param1 string) (phpredishandler.MemcachedConfig, error) {
+	f.ParseCall.mutex.Lock()
+	defer f.ParseCall.mutex.Unlock()
+	f.ParseCall.CallCount++
+	f.ParseCall.Receives.Dir = param1
+	if f.ParseCall.Stub != nil {
+		return f.ParseCall.Stub(param1)
+	}
+	return f.ParseCall.Returns.MemcachedConfig, f.ParseCall.Returns.Error
+}
@@ -0,0 +1,39 @@
+package fakes
+
+import (
+	"sync"
+
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+)
+
+type MemcachedWriter struct {
+	WriteCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			MemcachedConfig phpredishandler.MemcachedConfig
+			Extensions      []string
+			LayerPath       string
+			CnbPath         string
+		}
+		Returns struct {
+			String string
+			Error  error
+		}
+		Stub func(phpredishandler.MemcachedConfig, []string, string, string) (string, error)
+	}
+}
+
+func (f *MemcachedWriter) Write(param1 phpredishandler.MemcachedConfig, param2 []string, param3 string, param4 string) (string, error) {
+	f.WriteCall.mutex.Lock()
+	defer f.WriteCall.mutex.Unlock()
+	f.WriteCall.CallCount++
+	f.WriteCall.Receives.MemcachedConfig = param1
+	f.WriteCall.Receives.Extensions = param2
+	f.WriteCall.Receives.LayerPath = param3
+	f.WriteCall.Receives.CnbPath = param4
+	if f.WriteCall.Stub != nil {
+		return f.WriteCall.Stub(param1, param2, param3, param4)
+	}
+	return f.WriteCall.Returns.String, f.WriteCall.Returns.Error
+}
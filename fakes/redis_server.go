@@ -0,0 +1,470 @@
+package fakes
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedisServer is an in-memory Redis server that speaks enough RESP to exercise
+// the session tooling in tests.
+type RedisServer struct {
+	Password string
+
+	// Latency delays every reply, to exercise client timeouts.
+	Latency time.Duration
+
+	mutex       sync.Mutex
+	closed      bool
+	listener    net.Listener
+	certificate []byte
+	conns       []net.Conn
+	entries     map[string]redisEntry
+	commands    [][]string
+}
+
+type redisEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewRedisServer() (*RedisServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	return newRedisServer(listener, nil), nil
+}
+
+// NewTLSRedisServer returns a server that only accepts TLS connections. It
+// presents a self-signed certificate for 127.0.0.1, which Certificate returns.
+func NewTLSRedisServer() (*RedisServer, error) {
+	certificate, key, err := selfSignedCertificate()
+	if err != nil {
+		return nil, err
+	}
+
+	keyPair, err := tls.X509KeyPair(certificate, key)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{keyPair},
+		MinVersion:   tls.VersionTLS12,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newRedisServer(listener, certificate), nil
+}
+
+func newRedisServer(listener net.Listener, certificate []byte) *RedisServer {
+	server := &RedisServer{
+		listener:    listener,
+		certificate: certificate,
+		entries:     map[string]redisEntry{},
+	}
+
+	go server.serve()
+
+	return server
+}
+
+func selfSignedCertificate() ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+		nil
+}
+
+// Certificate returns the PEM encoded certificate of a TLS server, and nil
+// otherwise.
+func (s *RedisServer) Certificate() []byte {
+	return s.certificate
+}
+
+func (s *RedisServer) Hostname() string {
+	return s.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+func (s *RedisServer) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Close stops the server and drops every open connection. It is safe to call
+// more than once.
+func (s *RedisServer) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+
+	return s.listener.Close()
+}
+
+// Seed stores a key directly, bypassing the network. A zero ttl stores the key
+// without an expiry.
+func (s *RedisServer) Seed(key string, value []byte, ttl time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry := redisEntry{value: value}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	s.entries[key] = entry
+}
+
+// Lookup returns the value and remaining time to live of a key.
+func (s *RedisServer) Lookup(key string) ([]byte, time.Duration, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, 0, false
+	}
+
+	var ttl time.Duration
+	if !entry.expiresAt.IsZero() {
+		ttl = time.Until(entry.expiresAt)
+	}
+
+	return entry.value, ttl, true
+}
+
+// Keys returns the sorted names of every stored key.
+func (s *RedisServer) Keys() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var keys []string
+	for key := range s.entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Commands returns every command received by the server, in order.
+func (s *RedisServer) Commands() [][]string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([][]string{}, s.commands...)
+}
+
+func (s *RedisServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mutex.Lock()
+		if s.closed {
+			s.mutex.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.conns = append(s.conns, conn)
+		s.mutex.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+func (s *RedisServer) handle(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+	authenticated := s.Password == ""
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		s.mutex.Lock()
+		s.commands = append(s.commands, args)
+		s.mutex.Unlock()
+
+		name := strings.ToUpper(args[0])
+		if name == "AUTH" {
+			if len(args) == 2 && args[1] == s.Password {
+				authenticated = true
+				_, _ = io.WriteString(conn, "+OK\r\n")
+			} else {
+				_, _ = io.WriteString(conn, "-WRONGPASS invalid username-password pair\r\n")
+			}
+			continue
+		}
+
+		if !authenticated {
+			_, _ = io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+
+		time.Sleep(s.Latency)
+		_, _ = io.WriteString(conn, s.execute(name, args[1:]))
+	}
+}
+
+func (s *RedisServer) execute(name string, args []string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.expire()
+
+	switch name {
+	case "PING":
+		return "+PONG\r\n"
+
+	case "SELECT":
+		index, err := strconv.Atoi(args[0])
+		if err != nil || index < 0 || index > 15 {
+			return "-ERR DB index is out of range\r\n"
+		}
+		return "+OK\r\n"
+
+	case "SET":
+		entry := redisEntry{value: []byte(args[1])}
+		for i := 2; i+1 < len(args); i += 2 {
+			amount, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return "-ERR value is not an integer or out of range\r\n"
+			}
+
+			switch strings.ToUpper(args[i]) {
+			case "EX":
+				entry.expiresAt = time.Now().Add(time.Duration(amount) * time.Second)
+			case "PX":
+				entry.expiresAt = time.Now().Add(time.Duration(amount) * time.Millisecond)
+			}
+		}
+		s.entries[args[0]] = entry
+		return "+OK\r\n"
+
+	case "GET":
+		entry, ok := s.entries[args[0]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(entry.value)
+
+	case "DEL":
+		var count int
+		for _, key := range args {
+			if _, ok := s.entries[key]; ok {
+				delete(s.entries, key)
+				count++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", count)
+
+	case "PTTL":
+		entry, ok := s.entries[args[0]]
+		if !ok {
+			return ":-2\r\n"
+		}
+		if entry.expiresAt.IsZero() {
+			return ":-1\r\n"
+		}
+		return fmt.Sprintf(":%d\r\n", time.Until(entry.expiresAt).Milliseconds())
+
+	case "DUMP":
+		entry, ok := s.entries[args[0]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(append([]byte("DUMP:"), entry.value...))
+
+	case "RESTORE":
+		payload := []byte(args[2])
+		if !strings.HasPrefix(args[2], "DUMP:") {
+			return "-ERR DUMP payload version or checksum are wrong\r\n"
+		}
+
+		replace := len(args) > 3 && strings.ToUpper(args[3]) == "REPLACE"
+		if _, ok := s.entries[args[0]]; ok && !replace {
+			return "-BUSYKEY Target key name already exists.\r\n"
+		}
+
+		ttl, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return "-ERR value is not an integer or out of range\r\n"
+		}
+
+		entry := redisEntry{value: payload[len("DUMP:"):]}
+		if ttl > 0 {
+			entry.expiresAt = time.Now().Add(time.Duration(ttl) * time.Millisecond)
+		}
+		s.entries[args[0]] = entry
+		return "+OK\r\n"
+
+	case "SCAN":
+		cursor, err := strconv.Atoi(args[0])
+		if err != nil {
+			return "-ERR invalid cursor\r\n"
+		}
+
+		match, count := "*", 10
+		for i := 1; i+1 < len(args); i += 2 {
+			switch strings.ToUpper(args[i]) {
+			case "MATCH":
+				match = args[i+1]
+			case "COUNT":
+				count, err = strconv.Atoi(args[i+1])
+				if err != nil {
+					return "-ERR value is not an integer or out of range\r\n"
+				}
+			}
+		}
+
+		var keys []string
+		for key := range s.entries {
+			if ok, _ := path.Match(match, key); ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		if cursor > len(keys) {
+			cursor = len(keys)
+		}
+
+		next := cursor + count
+		if next >= len(keys) {
+			next = 0
+			count = len(keys) - cursor
+		}
+
+		var reply strings.Builder
+		reply.WriteString("*2\r\n")
+		reply.WriteString(bulk([]byte(strconv.Itoa(next))))
+		reply.WriteString(fmt.Sprintf("*%d\r\n", count))
+		for _, key := range keys[cursor : cursor+count] {
+			reply.WriteString(bulk([]byte(key)))
+		}
+		return reply.String()
+
+	case "MEMORY":
+		if len(args) == 2 && strings.ToUpper(args[0]) == "USAGE" {
+			entry, ok := s.entries[args[1]]
+			if !ok {
+				return "$-1\r\n"
+			}
+			return fmt.Sprintf(":%d\r\n", len(args[1])+len(entry.value))
+		}
+
+	case "INFO":
+		var used int
+		for key, entry := range s.entries {
+			used += len(key) + len(entry.value)
+		}
+		return bulk([]byte(fmt.Sprintf("# Memory\r\nused_memory:%d\r\nmaxmemory:0\r\n", used)))
+	}
+
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", name)
+}
+
+func (s *RedisServer) expire() {
+	for key, entry := range s.entries {
+		if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+func bulk(value []byte) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	for i := 0; i < count; i++ {
+		line, err = reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+
+		b := make([]byte, length+2)
+		_, err = io.ReadFull(reader, b)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, string(b[:length]))
+	}
+
+	return args, nil
+}
@@ -0,0 +1,95 @@
+package phpredishandler
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fpmPoolInclude is the pool file written into the application. It only
+// includes the pool configuration in the layer, so that the Redis password in
+// the save path stays out of the application directory.
+const fpmPoolInclude = `; Locks the session handler of a php-fpm pool. Generated by the
+; php-redis-session-handler buildpack; do not edit.
+include=%s
+`
+
+// WriteFpmPoolInclude writes FpmPoolFile into the FpmPoolDir of the
+// application, including the pool configuration at poolPath. It returns an
+// error when the application already has that file, or when fpmConfig, the
+// php-fpm configuration named by FpmConfigEnv, does not include it. An empty
+// fpmConfig means that php-fpm is configured by a later buildpack, which
+// includes FpmPoolDir because it exists by then.
+func WriteFpmPoolInclude(workingDir, poolPath, fpmConfig string) (string, error) {
+	path := filepath.Join(workingDir, FpmPoolDir, FpmPoolFile)
+	_, err := os.Lstat(path)
+	if err == nil {
+		return "", fmt.Errorf("the application already contains %s: rename it or unset %s", filepath.Join(FpmPoolDir, FpmPoolFile), FpmAdminEnv)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	if fpmConfig != "" {
+		included, err := fpmIncludes(fpmConfig, path)
+		if err != nil {
+			return "", err
+		}
+
+		if !included {
+			return "", fmt.Errorf("the php-fpm configuration %s does not include %s: add a %s directory to the application so that the php-fpm buildpack includes it, or unset %s", fpmConfig, path, FpmPoolDir, FpmAdminEnv)
+		}
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.WriteFile(path, []byte(fmt.Sprintf(fpmPoolInclude, poolPath)), os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// fpmIncludes reports whether an include directive of the php-fpm
+// configuration file matches path.
+func fpmIncludes(fpmConfig, path string) (bool, error) {
+	file, err := os.Open(fpmConfig)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the php-fpm configuration: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, value, found := strings.Cut(scanner.Text(), "=")
+		if !found || strings.TrimSpace(name) != "include" {
+			continue
+		}
+
+		matched, err := filepath.Match(iniValue(value), path)
+		if err != nil {
+			continue
+		}
+
+		if matched {
+			return true, nil
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		// not tested
+		return false, fmt.Errorf("failed to read %s: %w", fpmConfig, err)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,104 @@
+package phpredishandler_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testFpmPool(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		fpmConfig  string
+	)
+
+	it.Before(func() {
+		workingDir = t.TempDir()
+		fpmConfig = filepath.Join(t.TempDir(), "php-fpm.conf")
+		Expect(os.WriteFile(fpmConfig, []byte(`[global]
+pid = /tmp/php-fpm.pid
+include=/layers/php-fpm/buildpack.conf
+include = "`+filepath.Join(workingDir, ".php.fpm.d")+`/*.conf"
+`), os.ModePerm)).To(Succeed())
+	})
+
+	context("WriteFpmPoolInclude", func() {
+		it("writes a pool file that includes the pool configuration of the layer", func() {
+			path, err := phpredishandler.WriteFpmPoolInclude(workingDir, "/layers/php-redis-config/php-redis-session.conf", fpmConfig)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(workingDir, ".php.fpm.d", "php-redis-session.conf")))
+
+			contents, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal(`; Locks the session handler of a php-fpm pool. Generated by the
+; php-redis-session-handler buildpack; do not edit.
+include=/layers/php-redis-config/php-redis-session.conf
+`))
+		})
+
+		context("when php-fpm is configured by a later buildpack", func() {
+			it("writes the pool file", func() {
+				path, err := phpredishandler.WriteFpmPoolInclude(workingDir, "/layers/php-redis-config/php-redis-session.conf", "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(path).To(BeARegularFile())
+			})
+		})
+
+		context("when the php-fpm configuration does not include the pool file", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(fpmConfig, []byte("[global]\ninclude=/layers/php-fpm/buildpack.conf\n"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := phpredishandler.WriteFpmPoolInclude(workingDir, "/layers/php-redis-config/php-redis-session.conf", fpmConfig)
+				Expect(err).To(MatchError(ContainSubstring("the php-fpm configuration " + fpmConfig + " does not include " + filepath.Join(workingDir, ".php.fpm.d", "php-redis-session.conf"))))
+				Expect(filepath.Join(workingDir, ".php.fpm.d")).NotTo(BeADirectory())
+			})
+		})
+
+		context("when the php-fpm configuration cannot be read", func() {
+			it("returns an error", func() {
+				_, err := phpredishandler.WriteFpmPoolInclude(workingDir, "/layers/php-redis-config/php-redis-session.conf", filepath.Join(workingDir, "missing.conf"))
+				Expect(err).To(MatchError(ContainSubstring("failed to read the php-fpm configuration")))
+			})
+		})
+
+		context("when the application already contains the pool file", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(workingDir, ".php.fpm.d"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, ".php.fpm.d", "php-redis-session.conf"), []byte("[www]\n"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error and keeps the file", func() {
+				_, err := phpredishandler.WriteFpmPoolInclude(workingDir, "/layers/php-redis-config/php-redis-session.conf", fpmConfig)
+				Expect(err).To(MatchError("the application already contains .php.fpm.d/php-redis-session.conf: rename it or unset BP_PHP_REDIS_SESSION_FPM_ADMIN"))
+
+				contents, err := os.ReadFile(filepath.Join(workingDir, ".php.fpm.d", "php-redis-session.conf"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal("[www]\n"))
+			})
+		})
+
+		context("when the application directory is not writable", func() {
+			it.Before(func() {
+				Expect(os.Chmod(workingDir, 0500)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Chmod(workingDir, os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := phpredishandler.WriteFpmPoolInclude(workingDir, "/layers/php-redis-config/php-redis-session.conf", fpmConfig)
+				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+			})
+		})
+	})
+}
@@ -0,0 +1,222 @@
+package phpredishandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	FrameworkLaravel = "laravel"
+	FrameworkSymfony = "symfony"
+	FrameworkDrupal  = "drupal"
+
+	// FrameworkAuto detects the framework from composer.lock or
+	// composer.json, and FrameworkNone turns the integration off.
+	FrameworkAuto = "auto"
+	FrameworkNone = "none"
+)
+
+// FrameworkSecrets are the launch environment variables of the frameworks
+// that contain the password.
+var FrameworkSecrets = []string{"REDIS_PASSWORD", "REDIS_URL", "SESSION_HANDLER_DSN"}
+
+// Framework is a PHP framework that configures its sessions itself instead of
+// with session.save_handler.
+type Framework struct {
+	Name  string
+	Title string
+
+	// Packages are the composer packages that identify the framework.
+	Packages []string
+
+	// Note is logged after the environment variables, for frameworks that
+	// need more than the environment to use them.
+	Note string
+
+	environment func(RedisConfig, SessionClient) map[string]string
+}
+
+var frameworks = []Framework{
+	{
+		Name:        FrameworkLaravel,
+		Title:       "Laravel",
+		Packages:    []string{"laravel/framework"},
+		environment: laravelEnvironment,
+	},
+	{
+		Name:        FrameworkDrupal,
+		Title:       "Drupal",
+		Packages:    []string{"drupal/core", "drupal/core-recommended"},
+		Note:        "settings.php must configure the redis module from REDIS_HOST, REDIS_PORT and REDIS_PASSWORD",
+		environment: hostEnvironment,
+	},
+	{
+		Name:        FrameworkSymfony,
+		Title:       "Symfony",
+		Packages:    []string{"symfony/framework-bundle"},
+		Note:        "set framework.session.handler_id to %env(SESSION_HANDLER_DSN)% to use it",
+		environment: symfonyEnvironment,
+	},
+}
+
+// Environment returns the launch environment variables that point the
+// framework at the Redis server of the config.
+func (f Framework) Environment(config RedisConfig, client SessionClient) map[string]string {
+	return f.environment(config, client)
+}
+
+// LookupFramework returns the named framework.
+func LookupFramework(name string) (Framework, error) {
+	for _, framework := range frameworks {
+		if framework.Name == name {
+			return framework, nil
+		}
+	}
+
+	return Framework{}, fmt.Errorf("unknown framework %q: must be one of %q, %q, %q, %q or %q", name, FrameworkAuto, FrameworkNone, FrameworkLaravel, FrameworkSymfony, FrameworkDrupal)
+}
+
+// SelectFramework returns the framework selected with FrameworkEnv and the
+// file it was detected from, if any. It returns no framework when the
+// integration is turned off or no framework is detected.
+func SelectFramework(workingDir string, environment Environment) (Framework, string, error) {
+	name, _ := environment.Lookup(FrameworkEnv)
+	switch name {
+	case FrameworkNone:
+		return Framework{}, "", nil
+	case "", FrameworkAuto:
+		return DetectFramework(workingDir)
+	}
+
+	framework, err := LookupFramework(name)
+	if err != nil {
+		return Framework{}, "", fmt.Errorf("invalid %s: %w", FrameworkEnv, err)
+	}
+
+	return framework, "", nil
+}
+
+// DetectFramework detects the framework from the packages installed in
+// composer.lock or, without a lock file, required in composer.json. It
+// returns the framework and the file it was detected from.
+func DetectFramework(workingDir string) (Framework, string, error) {
+	for _, file := range []string{"composer.lock", "composer.json"} {
+		packages, err := composerPackages(filepath.Join(workingDir, file))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return Framework{}, "", err
+		}
+
+		for _, framework := range frameworks {
+			for _, name := range framework.Packages {
+				if packages[name] {
+					return framework, file, nil
+				}
+			}
+		}
+
+		return Framework{}, "", nil
+	}
+
+	return Framework{}, "", nil
+}
+
+// composerPackages returns the names of the packages of a composer.lock, or
+// of the packages required by a composer.json.
+func composerPackages(path string) (map[string]bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var composer struct {
+		Packages []struct {
+			Name string `json:"name"`
+		} `json:"packages"`
+		Require map[string]string `json:"require"`
+	}
+	err = json.Unmarshal(content, &composer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	packages := map[string]bool{}
+	for _, p := range composer.Packages {
+		packages[p.Name] = true
+	}
+
+	for name := range composer.Require {
+		packages[name] = true
+	}
+
+	return packages, nil
+}
+
+// hostEnvironment returns the REDIS_HOST, REDIS_PORT and REDIS_PASSWORD
+// variables most frameworks read.
+func hostEnvironment(config RedisConfig, _ SessionClient) map[string]string {
+	env := map[string]string{
+		"REDIS_HOST": config.Hostname,
+		"REDIS_PORT": strconv.Itoa(config.Port),
+	}
+	if config.Password != "" {
+		env["REDIS_PASSWORD"] = config.Password
+	}
+
+	return env
+}
+
+// laravelEnvironment selects the redis session driver, a database other than
+// 0 and, for the clients Laravel supports, the Redis client. A TLS connection is selected
+// with the tls:// scheme of the host.
+func laravelEnvironment(config RedisConfig, client SessionClient) map[string]string {
+	env := hostEnvironment(config, client)
+	env["SESSION_DRIVER"] = "redis"
+	if config.Database != 0 {
+		env["REDIS_DB"] = strconv.Itoa(config.Database)
+	}
+
+	if config.TLS {
+		env["REDIS_HOST"] = "tls://" + config.Hostname
+	}
+
+	if client.Name == SessionClientPhpRedis || client.Name == SessionClientPredis {
+		env["REDIS_CLIENT"] = client.Name
+	}
+
+	return env
+}
+
+// symfonyEnvironment returns the DSN of the Redis server in the format of the
+// Symfony cache component, which RedisSessionHandler accepts as the
+// session handler_id.
+func symfonyEnvironment(config RedisConfig, _ SessionClient) map[string]string {
+	address := fmt.Sprintf("%s:%d", config.Hostname, config.Port)
+
+	scheme := "redis://"
+	if config.TLS {
+		scheme = "rediss://"
+	}
+
+	if config.Database != 0 {
+		address += "/" + strconv.Itoa(config.Database)
+	}
+
+	dsn := scheme + address
+	if config.Password != "" {
+		dsn = scheme + url.User(config.Password).String() + "@" + address
+	}
+
+	return map[string]string{
+		"REDIS_URL":           dsn,
+		"SESSION_HANDLER_DSN": dsn,
+	}
+}
@@ -0,0 +1,164 @@
+package phpredishandler_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testFramework(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		config     phpredishandler.RedisConfig
+		client     phpredishandler.SessionClient
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = os.MkdirTemp("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		config = phpredishandler.RedisConfig{
+			Hostname: "some-host",
+			Port:     6379,
+			Password: "some-password",
+		}
+
+		client, err = phpredishandler.LookupSessionClient(phpredishandler.SessionClientPhpRedis)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	context("DetectFramework", func() {
+		it("detects the framework from the packages of composer.lock", func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "composer.lock"), []byte(`{"packages": [{"name": "symfony/http-kernel"}, {"name": "symfony/framework-bundle"}]}`), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "composer.json"), []byte(`{"require": {"laravel/framework": "^11.0"}}`), os.ModePerm)).To(Succeed())
+
+			framework, source, err := phpredishandler.DetectFramework(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(framework.Name).To(Equal("symfony"))
+			Expect(source).To(Equal("composer.lock"))
+		})
+
+		it("falls back to the requirements of composer.json", func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "composer.json"), []byte(`{"require": {"php": "^8.2", "drupal/core-recommended": "^10.3"}}`), os.ModePerm)).To(Succeed())
+
+			framework, source, err := phpredishandler.DetectFramework(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(framework.Name).To(Equal("drupal"))
+			Expect(source).To(Equal("composer.json"))
+		})
+
+		it("prefers Laravel and Drupal over the Symfony components they require", func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "composer.lock"), []byte(`{"packages": [{"name": "symfony/framework-bundle"}, {"name": "laravel/framework"}]}`), os.ModePerm)).To(Succeed())
+
+			framework, _, err := phpredishandler.DetectFramework(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(framework.Name).To(Equal("laravel"))
+		})
+
+		context("when there is no composer file or no framework", func() {
+			it("returns no framework", func() {
+				framework, _, err := phpredishandler.DetectFramework(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(framework.Name).To(BeEmpty())
+
+				Expect(os.WriteFile(filepath.Join(workingDir, "composer.json"), []byte(`{"require": {"php": "^8.2"}}`), os.ModePerm)).To(Succeed())
+
+				framework, _, err = phpredishandler.DetectFramework(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(framework.Name).To(BeEmpty())
+			})
+		})
+
+		context("when composer.lock is not valid JSON", func() {
+			it("returns an error", func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "composer.lock"), []byte("%%%"), os.ModePerm)).To(Succeed())
+
+				_, _, err := phpredishandler.DetectFramework(workingDir)
+				Expect(err).To(MatchError(ContainSubstring("failed to parse")))
+			})
+		})
+	})
+
+	context("SelectFramework", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "composer.json"), []byte(`{"require": {"laravel/framework": "^11.0"}}`), os.ModePerm)).To(Succeed())
+		})
+
+		it("detects the framework by default", func() {
+			framework, source, err := phpredishandler.SelectFramework(workingDir, phpredishandler.Environment{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(framework.Name).To(Equal("laravel"))
+			Expect(source).To(Equal("composer.json"))
+		})
+
+		it("turns the integration off with none", func() {
+			framework, _, err := phpredishandler.SelectFramework(workingDir, phpredishandler.Environment{"BP_PHP_REDIS_SESSION_FRAMEWORK": "none"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(framework.Name).To(BeEmpty())
+		})
+
+		it("forces the named framework", func() {
+			framework, source, err := phpredishandler.SelectFramework(workingDir, phpredishandler.Environment{"BP_PHP_REDIS_SESSION_FRAMEWORK": "drupal"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(framework.Name).To(Equal("drupal"))
+			Expect(source).To(BeEmpty())
+		})
+
+		context("when the framework is unknown", func() {
+			it("returns an error", func() {
+				_, _, err := phpredishandler.SelectFramework(workingDir, phpredishandler.Environment{"BP_PHP_REDIS_SESSION_FRAMEWORK": "rails"})
+				Expect(err).To(MatchError(`invalid BP_PHP_REDIS_SESSION_FRAMEWORK: unknown framework "rails": must be one of "auto", "none", "laravel", "symfony" or "drupal"`))
+			})
+		})
+	})
+
+	context("Environment", func() {
+		it("selects the redis session driver of Laravel", func() {
+			framework, err := phpredishandler.LookupFramework("laravel")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(framework.Environment(config, client)).To(Equal(map[string]string{
+				"SESSION_DRIVER": "redis",
+				"REDIS_CLIENT":   "phpredis",
+				"REDIS_HOST":     "some-host",
+				"REDIS_PORT":     "6379",
+				"REDIS_PASSWORD": "some-password",
+			}))
+
+			config.TLS = true
+			config.Database = 2
+			Expect(framework.Environment(config, client)).To(And(
+				HaveKeyWithValue("REDIS_HOST", "tls://some-host"),
+				HaveKeyWithValue("REDIS_DB", "2"),
+			))
+		})
+
+		it("sets the Symfony session handler DSN", func() {
+			framework, err := phpredishandler.LookupFramework("symfony")
+			Expect(err).NotTo(HaveOccurred())
+
+			config.Password = "some pass"
+			Expect(framework.Environment(config, client)).To(Equal(map[string]string{
+				"REDIS_URL":           "redis://some%20pass@some-host:6379",
+				"SESSION_HANDLER_DSN": "redis://some%20pass@some-host:6379",
+			}))
+
+			config.Password = ""
+			config.TLS = true
+			config.Database = 2
+			Expect(framework.Environment(config, client)).To(HaveKeyWithValue("SESSION_HANDLER_DSN", "rediss://some-host:6379/2"))
+		})
+	})
+}
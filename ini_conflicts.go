@@ -0,0 +1,202 @@
+package phpredishandler
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The policies for session directives that other ini files set to a different
+// value, selected with IniConflictsEnv.
+const (
+	IniConflictsWarn     = "warn"
+	IniConflictsFail     = "fail"
+	IniConflictsOverride = "override"
+)
+
+// AppIniDir is the directory, relative to the application's working
+// directory, that holds the application's own ini snippets.
+const AppIniDir = ".php.ini.d"
+
+// IniDirective is a session.* or redis.* directive read from an ini file.
+type IniDirective struct {
+	File  string
+	Line  int
+	Name  string
+	Value string
+}
+
+// IniConflict is a directive of another ini file that sets a directive
+// written by this buildpack to a different value.
+type IniConflict struct {
+	IniDirective
+
+	// Expected is the value written by this buildpack.
+	Expected string
+}
+
+// Redacted returns the conflict with the passwords in both values replaced,
+// so that it can be safely printed.
+func (c IniConflict) Redacted() IniConflict {
+	c.Value = RedactSecrets(c.Value)
+	c.Expected = RedactSecrets(c.Expected)
+
+	return c
+}
+
+func (c IniConflict) String() string {
+	c = c.Redacted()
+	return fmt.Sprintf("%s:%d sets %s to %q instead of %q", c.File, c.Line, c.Name, c.Value, c.Expected)
+}
+
+// IniConflictsPolicy returns the policy selected with IniConflictsEnv, which
+// defaults to IniConflictsWarn.
+func IniConflictsPolicy(environment Environment) (string, error) {
+	policy, ok := environment.Lookup(IniConflictsEnv)
+	if !ok || policy == "" {
+		return IniConflictsWarn, nil
+	}
+
+	switch policy {
+	case IniConflictsWarn, IniConflictsFail, IniConflictsOverride:
+		return policy, nil
+	}
+
+	return "", fmt.Errorf("invalid %s %q: must be one of %q, %q or %q", IniConflictsEnv, policy, IniConflictsWarn, IniConflictsFail, IniConflictsOverride)
+}
+
+// ScanIniDirectives reads the session.* and redis.* directives of the given
+// ini files, in order. Directories are expanded to the *.ini files they
+// contain in the alphabetical order PHP scans them in, and paths that do not
+// exist are skipped.
+func ScanIniDirectives(paths []string) ([]IniDirective, error) {
+	var directives []IniDirective
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		files := []string{path}
+		if info.IsDir() {
+			// Glob returns the matches in lexical order.
+			files, err = filepath.Glob(filepath.Join(path, "*.ini"))
+			if err != nil {
+				// not tested
+				return nil, err
+			}
+		}
+
+		for _, file := range files {
+			fileDirectives, err := parseIniDirectives(file)
+			if err != nil {
+				return nil, err
+			}
+
+			directives = append(directives, fileDirectives...)
+		}
+	}
+
+	return directives, nil
+}
+
+func parseIniDirectives(path string) ([]IniDirective, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var directives []IniDirective
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		name, value, found := strings.Cut(scanner.Text(), "=")
+		if !found {
+			continue
+		}
+
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !strings.HasPrefix(name, "session.") && !strings.HasPrefix(name, "redis.") {
+			continue
+		}
+
+		directives = append(directives, IniDirective{
+			File:  path,
+			Line:  line,
+			Name:  name,
+			Value: iniValue(value),
+		})
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		// not tested
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return directives, nil
+}
+
+// iniValue returns the value of a directive without its quotes and trailing
+// comment.
+func iniValue(value string) string {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, `"`) {
+		if end := strings.Index(value[1:], `"`); end >= 0 {
+			return value[1 : end+1]
+		}
+	}
+
+	value, _, _ = strings.Cut(value, ";")
+	return strings.TrimSpace(value)
+}
+
+// FindIniConflicts returns the directives of others that set a directive of
+// ours to a different value. Boolean values are compared by meaning, so that
+// On and 1 do not conflict.
+func FindIniConflicts(ours, others []IniDirective) []IniConflict {
+	expected := map[string]string{}
+	for _, directive := range ours {
+		expected[directive.Name] = directive.Value
+	}
+
+	var conflicts []IniConflict
+	for _, directive := range others {
+		value, ok := expected[directive.Name]
+		if !ok || sameIniValue(value, directive.Value) {
+			continue
+		}
+
+		conflicts = append(conflicts, IniConflict{
+			IniDirective: directive,
+			Expected:     value,
+		})
+	}
+
+	return conflicts
+}
+
+func sameIniValue(a, b string) bool {
+	if strings.EqualFold(a, b) {
+		return true
+	}
+
+	aBool, aOk := iniBools[strings.ToLower(a)]
+	bBool, bOk := iniBools[strings.ToLower(b)]
+
+	return aOk && bOk && aBool == bBool
+}
+
+var iniBools = map[string]bool{
+	"1": true, "on": true, "yes": true, "true": true,
+	"0": false, "off": false, "no": false, "false": false,
+}
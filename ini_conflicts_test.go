@@ -0,0 +1,121 @@
+package phpredishandler_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testIniConflicts(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		iniDir string
+	)
+
+	it.Before(func() {
+		iniDir = t.TempDir()
+	})
+
+	context("ScanIniDirectives", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(iniDir, "b.ini"), []byte(`[session]
+; session.save_handler = files
+session.save_handler = files ; the default
+Session.Name = "MY;SESSION"
+memory_limit = 128M
+redis.session.locking_enabled=1
+`), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(iniDir, "a.ini"), []byte("session.gc_maxlifetime = 60\n"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(iniDir, "notes.txt"), []byte("session.gc_maxlifetime = 30\n"), os.ModePerm)).To(Succeed())
+		})
+
+		it("reads the session and redis directives of the ini files in order", func() {
+			directives, err := phpredishandler.ScanIniDirectives([]string{
+				iniDir,
+				filepath.Join(iniDir, "a.ini"),
+				filepath.Join(iniDir, "missing.ini"),
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(directives).To(Equal([]phpredishandler.IniDirective{
+				{File: filepath.Join(iniDir, "a.ini"), Line: 1, Name: "session.gc_maxlifetime", Value: "60"},
+				{File: filepath.Join(iniDir, "b.ini"), Line: 3, Name: "session.save_handler", Value: "files"},
+				{File: filepath.Join(iniDir, "b.ini"), Line: 4, Name: "session.name", Value: "MY;SESSION"},
+				{File: filepath.Join(iniDir, "b.ini"), Line: 6, Name: "redis.session.locking_enabled", Value: "1"},
+				{File: filepath.Join(iniDir, "a.ini"), Line: 1, Name: "session.gc_maxlifetime", Value: "60"},
+			}))
+		})
+
+		context("when an ini file cannot be read", func() {
+			it.Before(func() {
+				Expect(os.Chmod(filepath.Join(iniDir, "a.ini"), 0000)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := phpredishandler.ScanIniDirectives([]string{iniDir})
+				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+			})
+		})
+	})
+
+	context("FindIniConflicts", func() {
+		it("returns the directives that set our directives to different values", func() {
+			ours := []phpredishandler.IniDirective{
+				{File: "php-redis.ini", Line: 1, Name: "session.save_handler", Value: "redis"},
+				{File: "php-redis.ini", Line: 2, Name: "session.cookie_secure", Value: "On"},
+				{File: "php-redis.ini", Line: 3, Name: "session.name", Value: "APP"},
+			}
+			others := []phpredishandler.IniDirective{
+				{File: "other.ini", Line: 1, Name: "session.save_handler", Value: "files"},
+				{File: "other.ini", Line: 2, Name: "session.cookie_secure", Value: "1"},
+				{File: "other.ini", Line: 3, Name: "session.name", Value: "app"},
+				{File: "other.ini", Line: 4, Name: "session.gc_maxlifetime", Value: "60"},
+			}
+
+			conflicts := phpredishandler.FindIniConflicts(ours, others)
+			Expect(conflicts).To(HaveLen(1))
+			Expect(conflicts[0].File).To(Equal("other.ini"))
+			Expect(conflicts[0].Expected).To(Equal("redis"))
+			Expect(conflicts[0].String()).To(Equal(`other.ini:1 sets session.save_handler to "files" instead of "redis"`))
+		})
+
+		it("redacts the passwords of conflicting save paths", func() {
+			conflicts := phpredishandler.FindIniConflicts(
+				[]phpredishandler.IniDirective{{File: "php-redis.ini", Line: 1, Name: "session.save_path", Value: "tcp://some-host:1234?auth=some-password"}},
+				[]phpredishandler.IniDirective{{File: "other.ini", Line: 1, Name: "session.save_path", Value: "tcp://other-host:1234?auth=other-password"}},
+			)
+			Expect(conflicts).To(HaveLen(1))
+			Expect(conflicts[0].Value).To(Equal("tcp://other-host:1234?auth=other-password"))
+			Expect(conflicts[0].Redacted().Value).To(Equal("tcp://other-host:1234?auth=REDACTED"))
+			Expect(conflicts[0].Redacted().Expected).To(Equal("tcp://some-host:1234?auth=REDACTED"))
+			Expect(conflicts[0].String()).To(Equal(`other.ini:1 sets session.save_path to "tcp://other-host:1234?auth=REDACTED" instead of "tcp://some-host:1234?auth=REDACTED"`))
+		})
+	})
+
+	context("IniConflictsPolicy", func() {
+		it("defaults to warn", func() {
+			policy, err := phpredishandler.IniConflictsPolicy(phpredishandler.Environment{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy).To(Equal("warn"))
+		})
+
+		it("returns the configured policy", func() {
+			policy, err := phpredishandler.IniConflictsPolicy(phpredishandler.Environment{"BP_PHP_REDIS_SESSION_INI_CONFLICTS": "fail"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(policy).To(Equal("fail"))
+		})
+
+		context("when the policy is unknown", func() {
+			it("returns an error", func() {
+				_, err := phpredishandler.IniConflictsPolicy(phpredishandler.Environment{"BP_PHP_REDIS_SESSION_INI_CONFLICTS": "ignore"})
+				Expect(err).To(MatchError(`invalid BP_PHP_REDIS_SESSION_INI_CONFLICTS "ignore": must be one of "warn", "fail" or "override"`))
+			})
+		})
+	})
+}
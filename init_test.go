@@ -10,8 +10,28 @@ import (
 func TestUnitPhpRedisHandler(t *testing.T) {
 	suite := spec.New("php-redis-handler", spec.Report(report.Terminal{}), spec.Parallel())
 	suite("Build", testBuild)
+	suite("BindingTypes", testBindingTypes)
 	suite("Detect", testDetect)
+	suite("Doctor", testDoctor)
+	suite("Environment", testEnvironment)
+	suite("ExportEnv", testExportEnv)
+	suite("FpmPool", testFpmPool)
+	suite("Framework", testFramework)
+	suite("IniConflicts", testIniConflicts)
+	suite("MemcachedConfigParser", testMemcachedConfigParser)
+	suite("MemcachedConfigWriter", testMemcachedConfigWriter)
+	suite("PhpExtensionInspector", testPhpExtensionInspector)
+	suite("ProcessSettings", testProcessSettings)
 	suite("RedisConfigParser", testRedisConfigParser)
+	suite("RedisClient", testRedisClient)
 	suite("RedisConfigWriter", testRedisConfigWriter)
+	suite("SessionClient", testSessionClient)
+	suite("SessionSettings", testSessionSettings)
+	suite("SessionCookie", testSessionCookie)
+	suite("SessionCopier", testSessionCopier)
+	suite("SessionMetricsExporter", testSessionMetricsExporter)
+	suite("SessionMigrator", testSessionMigrator)
+	suite("SessionScope", testSessionScope)
+	suite("SessionSwitch", testSessionSwitch)
 	suite.Run(t)
 }
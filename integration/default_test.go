@@ -91,7 +91,7 @@ func testDefault(t *testing.T, context spec.G, it spec.S) {
 			Expect(err).ToNot(HaveOccurred(), logs.String)
 			Expect(logs).To(ContainLines(
 				MatchRegexp(fmt.Sprintf(`%s \d+\.\d+\.\d+`, buildpackInfo.Buildpack.Name)),
-				"  Getting the layer associated with the redis configuration",
+				"  Getting the layer associated with the session configuration",
 				fmt.Sprintf("    /layers/%s/php-redis-config", strings.ReplaceAll(buildpackInfo.Buildpack.ID, "/", "_")),
 			))
 			Expect(logs).To(ContainLines(
@@ -100,6 +100,12 @@ func testDefault(t *testing.T, context spec.G, it spec.S) {
 			Expect(logs).To(ContainLines(
 				"  Parsing the php-redis-session service binding",
 			))
+			Expect(logs).To(ContainLines(
+				"  Inspecting the PHP installation",
+				MatchRegexp(`    PHP version: \d+\.\d+\.\d+`),
+				MatchRegexp(`    Extension directory: .+`),
+				"    Session client: phpredis",
+			))
 			Expect(logs).To(ContainLines(
 				"  Writing the redis configuration",
 				MatchRegexp(`    Including session save path: tcp:\/\/\d+\.\d+\.\d+\.\d+:6379`),
@@ -0,0 +1,149 @@
+package phpredishandler
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// MemcachedServer is a single entry of the memcached session save path.
+type MemcachedServer struct {
+	Hostname string
+	Port     int
+
+	// Weight is the relative share of sessions stored on the server. Zero
+	// leaves the weight unset.
+	Weight int
+}
+
+type MemcachedConfig struct {
+	Servers []MemcachedServer
+
+	// Username and Password are SASL credentials. SASL requires the binary
+	// protocol, which is enabled whenever a username is set.
+	Username string
+	Password string
+
+	// Sources records where each setting was read from, keyed by the
+	// setting name. Settings that were not configured map to SourceDefault.
+	Sources map[string]string
+}
+
+type MemcachedConfigParser struct {
+}
+
+func NewMemcachedConfigParser() MemcachedConfigParser {
+	return MemcachedConfigParser{}
+}
+
+// Parse reads a php-memcached-session binding. The servers entry lists one
+// server per line or comma, each as host, host:port or host:port:weight.
+func (p MemcachedConfigParser) Parse(dir string) (MemcachedConfig, error) {
+	sources := map[string]string{
+		"servers":  SourceDefault,
+		"username": SourceDefault,
+		"password": SourceDefault,
+	}
+
+	servers := []MemcachedServer{{Hostname: "127.0.0.1", Port: 11211}}
+
+	content, serversFileExists, err := readBindingFile(dir, "servers")
+	if err != nil {
+		return MemcachedConfig{}, err
+	}
+
+	if serversFileExists {
+		serversFilepath := filepath.Join(dir, "servers")
+		sources["servers"] = serversFilepath
+
+		servers = nil
+		for _, entry := range strings.FieldsFunc(content, func(r rune) bool { return r == ',' || r == '\n' }) {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+
+			server, err := parseMemcachedServer(entry)
+			if err != nil {
+				return MemcachedConfig{}, fmt.Errorf("invalid server %q in %s: %w", entry, serversFilepath, err)
+			}
+
+			servers = append(servers, server)
+		}
+
+		if len(servers) == 0 {
+			return MemcachedConfig{}, fmt.Errorf("invalid servers in %s: must list at least one server", serversFilepath)
+		}
+	}
+
+	username, usernameFileExists, err := readBindingFile(dir, "username")
+	if err != nil {
+		return MemcachedConfig{}, err
+	}
+
+	if usernameFileExists {
+		sources["username"] = filepath.Join(dir, "username")
+	}
+
+	password, passwordFileExists, err := readBindingFile(dir, "password")
+	if err != nil {
+		return MemcachedConfig{}, err
+	}
+
+	if passwordFileExists {
+		sources["password"] = filepath.Join(dir, "password")
+	}
+
+	// The credentials are rendered as quoted ini values, which cannot span
+	// lines.
+	credentials := map[string]string{"username": username, "password": password}
+	for _, name := range []string{"username", "password"} {
+		if strings.ContainsFunc(credentials[name], unicode.IsControl) {
+			return MemcachedConfig{}, fmt.Errorf("invalid %s in %s: must not contain line breaks or control characters", name, sources[name])
+		}
+	}
+
+	if password != "" && username == "" {
+		return MemcachedConfig{}, fmt.Errorf("invalid password in %s: SASL requires a username", sources["password"])
+	}
+
+	return MemcachedConfig{
+		Servers:  servers,
+		Username: username,
+		Password: password,
+		Sources:  sources,
+	}, nil
+}
+
+func parseMemcachedServer(entry string) (MemcachedServer, error) {
+	server := MemcachedServer{Port: 11211}
+
+	parts := strings.Split(entry, ":")
+	if len(parts) > 3 {
+		return MemcachedServer{}, fmt.Errorf("must be host, host:port or host:port:weight")
+	}
+
+	server.Hostname = parts[0]
+	if server.Hostname == "" || strings.ContainsAny(server.Hostname, " \t/?#&@") {
+		return MemcachedServer{}, fmt.Errorf("must be a hostname or IP address")
+	}
+
+	var err error
+	if len(parts) > 1 {
+		server.Port, err = strconv.Atoi(parts[1])
+		if err != nil || server.Port < 1 || server.Port > 65535 {
+			return MemcachedServer{}, fmt.Errorf("port must be between 1 and 65535")
+		}
+	}
+
+	if len(parts) > 2 {
+		server.Weight, err = strconv.Atoi(parts[2])
+		if err != nil || server.Weight < 1 {
+			return MemcachedServer{}, fmt.Errorf("weight must be a positive integer")
+		}
+	}
+
+	return server, nil
+}
@@ -0,0 +1,145 @@
+package phpredishandler_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+)
+
+func testMemcachedConfigParser(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		parser     phpredishandler.MemcachedConfigParser
+	)
+
+	it.Before(func() {
+		workingDir = t.TempDir()
+		parser = phpredishandler.NewMemcachedConfigParser()
+	})
+
+	it("returns the default configuration", func() {
+		config, err := parser.Parse(workingDir)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(config).To(Equal(phpredishandler.MemcachedConfig{
+			Servers: []phpredishandler.MemcachedServer{{Hostname: "127.0.0.1", Port: 11211}},
+			Sources: map[string]string{
+				"servers":  "default",
+				"username": "default",
+				"password": "default",
+			},
+		}))
+	})
+
+	context("when the servers file exists", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "servers"), []byte("host-a, host-b:11212\nhost-c:11213:3\n"), os.ModePerm)).To(Succeed())
+		})
+
+		it("reads every server with its port and weight", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(config.Servers).To(Equal([]phpredishandler.MemcachedServer{
+				{Hostname: "host-a", Port: 11211},
+				{Hostname: "host-b", Port: 11212},
+				{Hostname: "host-c", Port: 11213, Weight: 3},
+			}))
+			Expect(config.Sources).To(HaveKeyWithValue("servers", filepath.Join(workingDir, "servers")))
+		})
+	})
+
+	context("when SASL credentials are provided", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "username"), []byte("some-user\n"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "password"), []byte("some-password\n"), os.ModePerm)).To(Succeed())
+		})
+
+		it("reads the username and password", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(config.Username).To(Equal("some-user"))
+			Expect(config.Password).To(Equal("some-password"))
+			Expect(config.Sources).To(HaveKeyWithValue("username", filepath.Join(workingDir, "username")))
+			Expect(config.Sources).To(HaveKeyWithValue("password", filepath.Join(workingDir, "password")))
+		})
+	})
+
+	context("failure cases", func() {
+		context("when a server port is invalid", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "servers"), []byte("some-host:0"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(fmt.Sprintf(`invalid server "some-host:0" in %s: port must be between 1 and 65535`, filepath.Join(workingDir, "servers"))))
+			})
+		})
+
+		context("when a server weight is invalid", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "servers"), []byte("some-host:11211:heavy"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring("weight must be a positive integer")))
+			})
+		})
+
+		context("when the servers file is empty", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "servers"), []byte(" ,\n"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring("must list at least one server")))
+			})
+		})
+
+		context("when the password contains a line break", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "username"), []byte("some-user"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "password"), []byte("some\nsession.save_handler = files"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(fmt.Sprintf("invalid password in %s: must not contain line breaks or control characters", filepath.Join(workingDir, "password"))))
+			})
+		})
+
+		context("when a password is given without a username", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "password"), []byte("some-password"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring("SASL requires a username")))
+			})
+		})
+
+		context("when the servers file cannot be read", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "servers"), []byte("some-host"), 0000)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+			})
+		})
+	})
+}
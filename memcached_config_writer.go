@@ -0,0 +1,66 @@
+package phpredishandler
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// MemcachedExtensions are the PHP extensions the memcached session handler
+// needs.
+var MemcachedExtensions = []string{"memcached"}
+
+// phpMemcachedIniData is the data available to the php-memcached.ini
+// template.
+type phpMemcachedIniData struct {
+	SavePath   string
+	Extensions []string
+	Username   string
+	Password   string
+}
+
+type MemcachedConfigWriter struct {
+	logger scribe.Emitter
+}
+
+func NewMemcachedConfigWriter(logger scribe.Emitter) MemcachedConfigWriter {
+	return MemcachedConfigWriter{
+		logger: logger,
+	}
+}
+
+// Write renders php-memcached.ini into the layer. The extensions are the
+// values of the extension= lines the file needs to load.
+func (c MemcachedConfigWriter) Write(memcachedConfig MemcachedConfig, extensions []string, layerPath, cnbPath string) (string, error) {
+	tmpl, err := template.New("php-memcached.ini").Funcs(template.FuncMap{"quote": iniQuote}).ParseFiles(filepath.Join(cnbPath, "config", "php-memcached.ini"))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse PHP memcached config template: %w", err)
+	}
+
+	var servers []string
+	for _, server := range memcachedConfig.Servers {
+		entry := fmt.Sprintf("%s:%d", server.Hostname, server.Port)
+		if server.Weight > 0 {
+			entry = fmt.Sprintf("%s:%d", entry, server.Weight)
+		}
+
+		servers = append(servers, entry)
+	}
+
+	sessionSavePath := strings.Join(servers, ",")
+	c.logger.Debug.Subprocess("Including session save path: %s", sessionSavePath)
+
+	if memcachedConfig.Username != "" {
+		c.logger.Debug.Subprocess("Including SASL credentials for: %s", memcachedConfig.Username)
+	}
+
+	return writeTemplate(tmpl, phpMemcachedIniData{
+		SavePath:   sessionSavePath,
+		Extensions: extensions,
+		Username:   memcachedConfig.Username,
+		Password:   memcachedConfig.Password,
+	}, filepath.Join(layerPath, "php-memcached.ini"))
+}
@@ -0,0 +1,127 @@
+package phpredishandler_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testMemcachedConfigWriter(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		layerDir        string
+		cnbDir          string
+		memcachedConfig phpredishandler.MemcachedConfig
+		writer          phpredishandler.MemcachedConfigWriter
+	)
+
+	it.Before(func() {
+		layerDir = t.TempDir()
+		cnbDir = t.TempDir()
+
+		Expect(os.MkdirAll(filepath.Join(cnbDir, "config"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(cnbDir, "config", "php-memcached.ini"), []byte(`{{range .Extensions}}extension={{.}}
+{{end}}session.save_path = "{{.SavePath}}"{{if .Username}} {{quote .Username}}:{{quote .Password}}{{end}}`), os.ModePerm)).To(Succeed())
+
+		memcachedConfig = phpredishandler.MemcachedConfig{
+			Servers: []phpredishandler.MemcachedServer{
+				{Hostname: "host-a", Port: 11211},
+				{Hostname: "host-b", Port: 11212, Weight: 3},
+			},
+		}
+
+		writer = phpredishandler.NewMemcachedConfigWriter(scribe.NewEmitter(bytes.NewBuffer(nil)))
+	})
+
+	it("writes a memcached config ini file into the layer", func() {
+		path, err := writer.Write(memcachedConfig, []string{"memcached.so"}, layerDir, cnbDir)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(path).To(Equal(filepath.Join(layerDir, "php-memcached.ini")))
+
+		contents, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(string(contents)).To(Equal("extension=memcached.so\nsession.save_path = \"host-a:11211,host-b:11212:3\""))
+	})
+
+	context("when SASL credentials are configured", func() {
+		it.Before(func() {
+			memcachedConfig.Username = "some-user"
+			memcachedConfig.Password = `some-"pass\word${HOME}`
+		})
+
+		it("includes them quoted", func() {
+			path, err := writer.Write(memcachedConfig, nil, layerDir, cnbDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(contents)).To(Equal(`session.save_path = "host-a:11211,host-b:11212:3" "some-user":"some-\"pass\\word\${HOME}"`))
+		})
+	})
+
+	context("with the shipped template", func() {
+		it.Before(func() {
+			var err error
+			cnbDir, err = filepath.Abs(".")
+			Expect(err).NotTo(HaveOccurred())
+
+			memcachedConfig.Username = "some-user"
+			memcachedConfig.Password = `some "pass"word$`
+		})
+
+		it("renders every setting into php-memcached.ini", func() {
+			path, err := writer.Write(memcachedConfig, []string{"memcached.so"}, layerDir, cnbDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal(`extension=memcached.so
+
+[session]
+session.save_handler = memcached
+session.save_path = "host-a:11211,host-b:11212:3"
+session.name = PHPSESSID
+
+memcached.sess_binary_protocol = On
+memcached.sess_sasl_username = "some-user"
+memcached.sess_sasl_password = "some \"pass\"word\$"
+
+`))
+		})
+	})
+
+	context("RedactSecrets", func() {
+		it("replaces the SASL password", func() {
+			Expect(phpredishandler.RedactSecrets(`memcached.sess_sasl_password = "some-password"`)).To(Equal(
+				`memcached.sess_sasl_password = "REDACTED"`,
+			))
+			Expect(phpredishandler.RedactSecrets(`memcached.sess_sasl_password = "some-\"pass\\word"`)).To(Equal(
+				`memcached.sess_sasl_password = "REDACTED"`,
+			))
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the template is not parseable", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cnbDir, "config", "php-memcached.ini"), []byte(`{{.`), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := writer.Write(memcachedConfig, nil, layerDir, cnbDir)
+				Expect(err).To(MatchError(ContainSubstring("failed to parse PHP memcached config template")))
+			})
+		})
+	})
+}
@@ -0,0 +1,221 @@
+package phpredishandler
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/fs"
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+)
+
+//go:generate faux --interface Executable --output fakes/executable.go
+
+// inspectScript prints the PHP version, the extension directory and the
+// extensions already loaded by the existing ini files, one per line.
+const inspectScript = `echo PHP_VERSION, PHP_EOL, ini_get("extension_dir"), PHP_EOL, implode(",", get_loaded_extensions()), PHP_EOL;`
+
+// compressionScript prints the session compressions the loaded phpredis
+// extension was built with, one per line.
+const compressionScript = `foreach (["lzf", "zstd", "lz4"] as $c) { if (defined("Redis::COMPRESSION_" . strtoupper($c))) { echo $c, PHP_EOL; } }`
+
+// iniFilesScript prints the php.ini and the additional ini files PHP loads,
+// separated by commas.
+const iniFilesScript = `echo php_ini_loaded_file(), ",", php_ini_scanned_files();`
+
+// requiredModulePattern matches the startup warning PHP prints when an
+// extension depends on one that is not loaded.
+var requiredModulePattern = regexp.MustCompile(`Cannot load module "[^"]+" because required module "([^"]+)" is not loaded`)
+
+type Executable interface {
+	Execute(execution pexec.Execution) error
+}
+
+// PhpInstallation describes the PHP distribution installed by an earlier
+// buildpack.
+type PhpInstallation struct {
+	Version          string
+	ExtensionDir     string
+	LoadedExtensions []string
+}
+
+// ExtensionsToLoad returns the extension= values php-redis.ini needs for the
+// given extensions. Extensions that are already loaded are left out, and an
+// error is returned when an extension is neither loaded nor present in the
+// extension directory.
+func (i PhpInstallation) ExtensionsToLoad(names []string) ([]string, error) {
+	var extensions []string
+	for _, name := range names {
+		if i.Loaded(name) {
+			continue
+		}
+
+		file := name + ".so"
+		exists, err := fs.Exists(filepath.Join(i.ExtensionDir, file))
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			return nil, fmt.Errorf("PHP %s does not provide the %s extension: %s was not found in %s", i.Version, name, file, i.ExtensionDir)
+		}
+
+		extensions = append(extensions, file)
+	}
+
+	return extensions, nil
+}
+
+// MinorVersion returns the major and minor parts of the PHP version, for
+// example 8.1 for PHP 8.1.12.
+func (i PhpInstallation) MinorVersion() string {
+	parts := strings.SplitN(i.Version, ".", 3)
+	if len(parts) < 2 {
+		return i.Version
+	}
+
+	return parts[0] + "." + parts[1]
+}
+
+// AtLeast reports whether the PHP version is at least the given major and
+// minor version.
+func (i PhpInstallation) AtLeast(major, minor int) bool {
+	var actualMajor, actualMinor int
+	_, err := fmt.Sscanf(i.MinorVersion(), "%d.%d", &actualMajor, &actualMinor)
+	if err != nil {
+		return false
+	}
+
+	return actualMajor > major || actualMajor == major && actualMinor >= minor
+}
+
+// Loaded reports whether the named extension is already loaded.
+func (i PhpInstallation) Loaded(name string) bool {
+	for _, loaded := range i.LoadedExtensions {
+		if strings.EqualFold(loaded, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type PhpExtensionInspector struct {
+	php Executable
+}
+
+func NewPhpExtensionInspector(php Executable) PhpExtensionInspector {
+	return PhpExtensionInspector{
+		php: php,
+	}
+}
+
+// Inspect runs php with the ini files of the PHP layer to find its version,
+// extension directory and the extensions it already loads.
+func (i PhpExtensionInspector) Inspect() (PhpInstallation, error) {
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	err := i.php.Execute(pexec.Execution{
+		Args:   []string{"-r", inspectScript},
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	if err != nil {
+		return PhpInstallation{}, fmt.Errorf("failed to inspect the PHP installation: %w\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 3 {
+		return PhpInstallation{}, fmt.Errorf("failed to inspect the PHP installation: unexpected output %q", stdout.String())
+	}
+
+	installation := PhpInstallation{
+		Version:      strings.TrimSpace(lines[0]),
+		ExtensionDir: strings.TrimSpace(lines[1]),
+	}
+
+	for _, extension := range strings.Split(strings.TrimSpace(lines[2]), ",") {
+		if extension != "" {
+			installation.LoadedExtensions = append(installation.LoadedExtensions, extension)
+		}
+	}
+
+	return installation, nil
+}
+
+// RedisCompressions loads the given extension= values on top of the PHP ini
+// files and returns the session compressions phpredis supports.
+func (i PhpExtensionInspector) RedisCompressions(extensions []string) ([]string, error) {
+	var args []string
+	for _, extension := range extensions {
+		args = append(args, "-d", "extension="+extension)
+	}
+
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	err := i.php.Execute(pexec.Execution{
+		Args:   append(args, "-r", compressionScript),
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect the phpredis extension: %w\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.Fields(stdout.String()), nil
+}
+
+// RequiredExtensions loads the given extension= value on top of the PHP ini
+// files and returns the extensions it depends on that are not loaded, such as
+// igbinary for a phpredis build linked against it.
+func (i PhpExtensionInspector) RequiredExtensions(extension string) ([]string, error) {
+	// PHP reports startup errors on stdout or stderr, depending on its
+	// display_startup_errors and log settings.
+	output := bytes.NewBuffer(nil)
+	err := i.php.Execute(pexec.Execution{
+		Args:   []string{"-d", "extension=" + extension, "-r", ""},
+		Stdout: output,
+		Stderr: output,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the %s extension: %w\n%s", extension, err, strings.TrimSpace(output.String()))
+	}
+
+	var required []string
+	for _, match := range requiredModulePattern.FindAllStringSubmatch(output.String(), -1) {
+		if !slices.Contains(required, match[1]) {
+			required = append(required, match[1])
+		}
+	}
+
+	return required, nil
+}
+
+// IniFiles returns the php.ini and the additional ini files that the PHP
+// installation loads during the build, in the order PHP loads them. Ini
+// directories that are only added to the launch environment are not listed.
+func (i PhpExtensionInspector) IniFiles() ([]string, error) {
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	err := i.php.Execute(pexec.Execution{
+		Args:   []string{"-r", iniFilesScript},
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the PHP ini files: %w\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var files []string
+	for _, file := range strings.Split(stdout.String(), ",") {
+		file = strings.TrimSpace(file)
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,245 @@
+package phpredishandler_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/paketo-buildpacks/php-redis-session-handler/fakes"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testPhpExtensionInspector(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		php       *fakes.Executable
+		inspector phpredishandler.PhpExtensionInspector
+	)
+
+	it.Before(func() {
+		php = &fakes.Executable{}
+		php.ExecuteCall.Stub = func(execution pexec.Execution) error {
+			fmt.Fprintln(execution.Stdout, "8.1.12")
+			fmt.Fprintln(execution.Stdout, "/layers/php/lib/php/extensions/no-debug-non-zts-20210902")
+			fmt.Fprintln(execution.Stdout, "Core,date,igbinary")
+			return nil
+		}
+
+		inspector = phpredishandler.NewPhpExtensionInspector(php)
+	})
+
+	it("reports the version, extension directory and loaded extensions", func() {
+		installation, err := inspector.Inspect()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(installation).To(Equal(phpredishandler.PhpInstallation{
+			Version:          "8.1.12",
+			ExtensionDir:     "/layers/php/lib/php/extensions/no-debug-non-zts-20210902",
+			LoadedExtensions: []string{"Core", "date", "igbinary"},
+		}))
+
+		Expect(php.ExecuteCall.Receives.Execution.Args[0]).To(Equal("-r"))
+	})
+
+	context("when php fails", func() {
+		it.Before(func() {
+			php.ExecuteCall.Stub = func(execution pexec.Execution) error {
+				fmt.Fprintln(execution.Stderr, "some-php-error")
+				return errors.New("exit status 1")
+			}
+		})
+
+		it("returns an error including the php output", func() {
+			_, err := inspector.Inspect()
+			Expect(err).To(MatchError("failed to inspect the PHP installation: exit status 1\nsome-php-error"))
+		})
+	})
+
+	context("when php prints unexpected output", func() {
+		it.Before(func() {
+			php.ExecuteCall.Stub = func(execution pexec.Execution) error {
+				fmt.Fprintln(execution.Stdout, "8.1.12")
+				return nil
+			}
+		})
+
+		it("returns an error", func() {
+			_, err := inspector.Inspect()
+			Expect(err).To(MatchError(ContainSubstring("unexpected output")))
+		})
+	})
+
+	context("RedisCompressions", func() {
+		it.Before(func() {
+			php.ExecuteCall.Stub = func(execution pexec.Execution) error {
+				fmt.Fprintln(execution.Stdout, "lzf")
+				fmt.Fprintln(execution.Stdout, "zstd")
+				return nil
+			}
+		})
+
+		it("loads the extensions and returns the supported compressions", func() {
+			compressions, err := inspector.RedisCompressions([]string{"redis.so"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(compressions).To(Equal([]string{"lzf", "zstd"}))
+
+			Expect(php.ExecuteCall.Receives.Execution.Args[:3]).To(Equal([]string{"-d", "extension=redis.so", "-r"}))
+		})
+
+		context("when php fails", func() {
+			it.Before(func() {
+				php.ExecuteCall.Stub = func(execution pexec.Execution) error {
+					fmt.Fprintln(execution.Stderr, "some-php-error")
+					return errors.New("exit status 1")
+				}
+			})
+
+			it("returns an error including the php output", func() {
+				_, err := inspector.RedisCompressions(nil)
+				Expect(err).To(MatchError("failed to inspect the phpredis extension: exit status 1\nsome-php-error"))
+			})
+		})
+	})
+
+	context("RequiredExtensions", func() {
+		it.Before(func() {
+			php.ExecuteCall.Stub = func(execution pexec.Execution) error {
+				fmt.Fprintln(execution.Stdout, `PHP Warning:  Cannot load module "redis" because required module "igbinary" is not loaded in Unknown on line 0`)
+				fmt.Fprintln(execution.Stderr, `Warning: Cannot load module "redis" because required module "igbinary" is not loaded in Unknown on line 0`)
+				return nil
+			}
+		})
+
+		it("loads the extension and returns the modules it requires", func() {
+			required, err := inspector.RequiredExtensions("redis.so")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(required).To(Equal([]string{"igbinary"}))
+
+			Expect(php.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"-d", "extension=redis.so", "-r", ""}))
+		})
+
+		context("when the extension loads", func() {
+			it.Before(func() {
+				php.ExecuteCall.Stub = nil
+			})
+
+			it("returns no modules", func() {
+				required, err := inspector.RequiredExtensions("redis.so")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(required).To(BeEmpty())
+			})
+		})
+
+		context("when php fails", func() {
+			it.Before(func() {
+				php.ExecuteCall.Stub = func(execution pexec.Execution) error {
+					fmt.Fprintln(execution.Stderr, "some-php-error")
+					return errors.New("exit status 1")
+				}
+			})
+
+			it("returns an error including the php output", func() {
+				_, err := inspector.RequiredExtensions("redis.so")
+				Expect(err).To(MatchError("failed to load the redis.so extension: exit status 1\nsome-php-error"))
+			})
+		})
+	})
+
+	context("IniFiles", func() {
+		it.Before(func() {
+			php.ExecuteCall.Stub = func(execution pexec.Execution) error {
+				fmt.Fprint(execution.Stdout, "/layers/php/etc/php.ini,/layers/php/etc/php.ini.d/ext.ini,\n/layers/php/etc/php.ini.d/session.ini")
+				return nil
+			}
+		})
+
+		it("returns the loaded and scanned ini files", func() {
+			files, err := inspector.IniFiles()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(files).To(Equal([]string{
+				"/layers/php/etc/php.ini",
+				"/layers/php/etc/php.ini.d/ext.ini",
+				"/layers/php/etc/php.ini.d/session.ini",
+			}))
+		})
+
+		context("when PHP loads no ini files", func() {
+			it.Before(func() {
+				php.ExecuteCall.Stub = func(execution pexec.Execution) error {
+					fmt.Fprint(execution.Stdout, ",")
+					return nil
+				}
+			})
+
+			it("returns no files", func() {
+				files, err := inspector.IniFiles()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(files).To(BeEmpty())
+			})
+		})
+
+		context("when php fails", func() {
+			it.Before(func() {
+				php.ExecuteCall.Stub = func(execution pexec.Execution) error {
+					fmt.Fprintln(execution.Stderr, "some-php-error")
+					return errors.New("exit status 1")
+				}
+			})
+
+			it("returns an error including the php output", func() {
+				_, err := inspector.IniFiles()
+				Expect(err).To(MatchError("failed to list the PHP ini files: exit status 1\nsome-php-error"))
+			})
+		})
+	})
+
+	context("ExtensionsToLoad", func() {
+		var installation phpredishandler.PhpInstallation
+
+		it.Before(func() {
+			extensionDir := t.TempDir()
+			Expect(os.WriteFile(filepath.Join(extensionDir, "redis.so"), nil, 0600)).To(Succeed())
+
+			installation = phpredishandler.PhpInstallation{
+				Version:          "8.1.12",
+				ExtensionDir:     extensionDir,
+				LoadedExtensions: []string{"Core", "IGBINARY"},
+			}
+		})
+
+		it("skips loaded extensions and loads the others from the extension directory", func() {
+			extensions, err := installation.ExtensionsToLoad([]string{"redis", "igbinary"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(extensions).To(Equal([]string{"redis.so"}))
+		})
+
+		it("returns an error naming the PHP version when an extension is missing", func() {
+			_, err := installation.ExtensionsToLoad([]string{"redis", "memcached"})
+			Expect(err).To(MatchError(fmt.Sprintf("PHP 8.1.12 does not provide the memcached extension: memcached.so was not found in %s", installation.ExtensionDir)))
+		})
+	})
+
+	context("AtLeast", func() {
+		it("compares the major and minor version", func() {
+			installation := phpredishandler.PhpInstallation{Version: "8.4.1"}
+			Expect(installation.AtLeast(8, 4)).To(BeTrue())
+			Expect(installation.AtLeast(8, 5)).To(BeFalse())
+			Expect(installation.AtLeast(7, 9)).To(BeTrue())
+			Expect(phpredishandler.PhpInstallation{Version: "unknown"}.AtLeast(8, 1)).To(BeFalse())
+		})
+	})
+
+	context("MinorVersion", func() {
+		it("returns the major and minor version", func() {
+			Expect(phpredishandler.PhpInstallation{Version: "8.1.12"}.MinorVersion()).To(Equal("8.1"))
+			Expect(phpredishandler.PhpInstallation{Version: "8"}.MinorVersion()).To(Equal("8"))
+		})
+	})
+}
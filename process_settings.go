@@ -0,0 +1,53 @@
+package phpredishandler
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// SessionProcesses returns the process types listed in ProcessesEnv, which
+// get their own session configuration.
+func SessionProcesses(environment Environment) []string {
+	value, _ := environment.Lookup(ProcessesEnv)
+	return splitList(value)
+}
+
+// ProcessEnvironment returns the session settings of a process type. A
+// setting such as BP_PHP_REDIS_SESSION_GC_MAXLIFETIME is set for the worker
+// process type with BP_PHP_REDIS_SESSION_PROCESS_WORKER_GC_MAXLIFETIME, and
+// is returned under its usual name.
+func ProcessEnvironment(environment Environment, process string) Environment {
+	prefix := ProcessSettingsPrefix + processEnvName(process) + "_"
+
+	processEnvironment := Environment{}
+	for name, value := range environment {
+		setting, found := strings.CutPrefix(name, prefix)
+		if found {
+			processEnvironment[SettingsPrefix+setting] = value
+		}
+	}
+
+	return processEnvironment
+}
+
+// ProcessIniPath returns the directory in the layer that holds the session
+// configuration of a process type.
+func ProcessIniPath(layerPath, process string) string {
+	return filepath.Join(layerPath, ProcessIniDir, process)
+}
+
+// processEnvName returns the process type as it appears in environment
+// variable names: upper case, with every other character than letters and
+// digits replaced by an underscore.
+func processEnvName(process string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+
+		return '_'
+	}, process)
+}
@@ -0,0 +1,49 @@
+package phpredishandler_test
+
+import (
+	"testing"
+
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testProcessSettings(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	context("SessionProcesses", func() {
+		it("returns the listed process types", func() {
+			Expect(phpredishandler.SessionProcesses(phpredishandler.Environment{})).To(BeEmpty())
+			Expect(phpredishandler.SessionProcesses(phpredishandler.Environment{
+				"BP_PHP_REDIS_SESSION_PROCESSES": "worker, queue-worker",
+			})).To(Equal([]string{"worker", "queue-worker"}))
+		})
+	})
+
+	context("ProcessEnvironment", func() {
+		it("returns the settings of the process type under their usual names", func() {
+			environment := phpredishandler.Environment{
+				"BP_PHP_REDIS_SESSION_GC_MAXLIFETIME":                      "1440",
+				"BP_PHP_REDIS_SESSION_PROCESS_WORKER_GC_MAXLIFETIME":       "60",
+				"BP_PHP_REDIS_SESSION_PROCESS_WORKER_LOCKING_ENABLED":      "false",
+				"BP_PHP_REDIS_SESSION_PROCESS_QUEUE_WORKER_GC_MAXLIFETIME": "30",
+			}
+
+			Expect(phpredishandler.ProcessEnvironment(environment, "worker")).To(Equal(phpredishandler.Environment{
+				"BP_PHP_REDIS_SESSION_GC_MAXLIFETIME":  "60",
+				"BP_PHP_REDIS_SESSION_LOCKING_ENABLED": "false",
+			}))
+			Expect(phpredishandler.ProcessEnvironment(environment, "queue-worker")).To(Equal(phpredishandler.Environment{
+				"BP_PHP_REDIS_SESSION_GC_MAXLIFETIME": "30",
+			}))
+			Expect(phpredishandler.ProcessEnvironment(environment, "web")).To(BeEmpty())
+		})
+	})
+
+	context("ProcessIniPath", func() {
+		it("returns the directory of the process type in the layer", func() {
+			Expect(phpredishandler.ProcessIniPath("/layers/php-redis-config", "worker")).To(Equal("/layers/php-redis-config/processes/worker"))
+		})
+	})
+}
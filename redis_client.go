@@ -0,0 +1,364 @@
+package phpredishandler
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedisError is an error reply returned by the Redis server.
+type RedisError string
+
+func (e RedisError) Error() string {
+	return string(e)
+}
+
+// RedisClient is a minimal RESP client that implements the handful of
+// commands the session tooling needs. It is not safe for concurrent use.
+type RedisClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// DialRedis connects to the Redis server described by the given config, over
+// TLS when it is configured. It authenticates when a password is configured
+// and selects the database of the config.
+func DialRedis(config RedisConfig, timeout time.Duration) (*RedisClient, error) {
+	address := net.JoinHostPort(config.Hostname, strconv.Itoa(config.Port))
+	dialer := &net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+	var err error
+	if config.TLS {
+		var tlsConfig *tls.Config
+		tlsConfig, err = redisTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	client := &RedisClient{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+
+	if config.Password != "" {
+		_, err = client.Do("AUTH", config.Password)
+		if err != nil {
+			_ = client.Close()
+			return nil, fmt.Errorf("failed to authenticate with redis: %w", err)
+		}
+	}
+
+	if config.Database != 0 {
+		_, err = client.Do("SELECT", config.Database)
+		if err != nil {
+			_ = client.Close()
+			return nil, fmt.Errorf("failed to select redis database %d: %w", config.Database, err)
+		}
+	}
+
+	return client, nil
+}
+
+// redisTLSConfig verifies the server the way the session handler does: the
+// certificate is checked for Hostname unless TLSVerifyPeer is false, against
+// the TLSCAFile bundle when one is configured and the system roots otherwise.
+func redisTLSConfig(config RedisConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: config.Hostname,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if config.TLSVerifyPeer != nil && !*config.TLSVerifyPeer {
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	if config.TLSCAFile != "" {
+		bundle, err := os.ReadFile(config.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the redis CA bundle: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("the redis CA bundle %s contains no certificates", config.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// SetDeadline sets the time after which reads and writes of the connection
+// fail with a timeout.
+func (c *RedisClient) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+// Close closes the underlying connection.
+func (c *RedisClient) Close() error {
+	return c.conn.Close()
+}
+
+// Do sends a command and returns its reply. Replies are decoded as string
+// (simple strings), []byte (bulk strings), int64 (integers), []interface{}
+// (arrays) or nil (null replies). Error replies are returned as RedisError.
+func (c *RedisClient) Do(args ...interface{}) (interface{}, error) {
+	writer := bufio.NewWriter(c.conn)
+	fmt.Fprintf(writer, "*%d\r\n", len(args))
+	for _, arg := range args {
+		var b []byte
+		switch v := arg.(type) {
+		case []byte:
+			b = v
+		case string:
+			b = []byte(v)
+		case int:
+			b = []byte(strconv.Itoa(v))
+		case int64:
+			b = []byte(strconv.FormatInt(v, 10))
+		default:
+			b = []byte(fmt.Sprint(v))
+		}
+
+		fmt.Fprintf(writer, "$%d\r\n", len(b))
+		_, _ = writer.Write(b)
+		_, _ = writer.WriteString("\r\n")
+	}
+
+	err := writer.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	return readReply(c.reader)
+}
+
+// Set stores the value with the given time to live. A zero ttl stores the key
+// without an expiry.
+func (c *RedisClient) Set(key string, value []byte, ttl time.Duration) error {
+	args := []interface{}{"SET", key, value}
+	if ttl > 0 {
+		args = append(args, "PX", ttl.Milliseconds())
+	}
+
+	_, err := c.Do(args...)
+	return err
+}
+
+// Get returns the value stored at key, or nil when the key does not exist.
+func (c *RedisClient) Get(key string) ([]byte, error) {
+	reply, err := c.Do("GET", key)
+	if err != nil {
+		return nil, err
+	}
+
+	value, _ := reply.([]byte)
+	return value, nil
+}
+
+// PTTL returns the remaining time to live of a key. A zero duration means the
+// key has no expiry, and false is returned when the key does not exist.
+func (c *RedisClient) PTTL(key string) (time.Duration, bool, error) {
+	reply, err := c.Do("PTTL", key)
+	if err != nil {
+		return 0, false, err
+	}
+
+	ms, ok := reply.(int64)
+	if !ok {
+		return 0, false, fmt.Errorf("unexpected PTTL reply: %v", reply)
+	}
+
+	switch ms {
+	case -2:
+		return 0, false, nil
+	case -1:
+		return 0, true, nil
+	}
+
+	return time.Duration(ms) * time.Millisecond, true, nil
+}
+
+// Dump returns the serialized value stored at key, or nil when the key does
+// not exist.
+func (c *RedisClient) Dump(key string) ([]byte, error) {
+	reply, err := c.Do("DUMP", key)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, _ := reply.([]byte)
+	return payload, nil
+}
+
+// Restore creates key from a payload produced by Dump, replacing any existing
+// value. A zero ttl restores the key without an expiry.
+func (c *RedisClient) Restore(key string, payload []byte, ttl time.Duration) error {
+	_, err := c.Do("RESTORE", key, ttl.Milliseconds(), payload, "REPLACE")
+	return err
+}
+
+// MemoryUsage returns the number of bytes a key and its value use in memory,
+// or zero when the key does not exist.
+func (c *RedisClient) MemoryUsage(key string) (int64, error) {
+	reply, err := c.Do("MEMORY", "USAGE", key)
+	if err != nil {
+		return 0, err
+	}
+
+	usage, _ := reply.(int64)
+	return usage, nil
+}
+
+// Info returns the fields of the given INFO section.
+func (c *RedisClient) Info(section string) (map[string]string, error) {
+	reply, err := c.Do("INFO", section)
+	if err != nil {
+		return nil, err
+	}
+
+	content, ok := reply.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("unexpected INFO reply: %v", reply)
+	}
+
+	fields := map[string]string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, found := strings.Cut(line, ":")
+		if found {
+			fields[name] = value
+		}
+	}
+
+	return fields, nil
+}
+
+// ScanPrefix calls f with every key that starts with prefix, one SCAN page at
+// a time.
+func (c *RedisClient) ScanPrefix(prefix string, f func(keys []string) error) error {
+	cursor := "0"
+	for {
+		reply, err := c.Do("SCAN", cursor, "MATCH", escapeGlob(prefix)+"*", "COUNT", 100)
+		if err != nil {
+			return err
+		}
+
+		page, ok := reply.([]interface{})
+		if !ok || len(page) != 2 {
+			return fmt.Errorf("unexpected SCAN reply: %v", reply)
+		}
+
+		next, _ := page[0].([]byte)
+		elements, _ := page[1].([]interface{})
+
+		var keys []string
+		for _, element := range elements {
+			key, _ := element.([]byte)
+			keys = append(keys, string(key))
+		}
+
+		if len(keys) > 0 {
+			err = f(keys)
+			if err != nil {
+				return err
+			}
+		}
+
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+// escapeGlob escapes the characters that Redis treats as glob patterns.
+func escapeGlob(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`).Replace(s)
+}
+
+func readReply(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("malformed redis reply: %q", line)
+	}
+	payload := line[1 : len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return payload, nil
+
+	case '-':
+		return nil, RedisError(payload)
+
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+
+	case '$':
+		length, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("malformed redis bulk length: %w", err)
+		}
+
+		if length < 0 {
+			return nil, nil
+		}
+
+		b := make([]byte, length+2)
+		_, err = io.ReadFull(reader, b)
+		if err != nil {
+			return nil, err
+		}
+
+		return b[:length], nil
+
+	case '*':
+		length, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("malformed redis array length: %w", err)
+		}
+
+		if length < 0 {
+			return nil, nil
+		}
+
+		var elements []interface{}
+		for i := 0; i < length; i++ {
+			element, err := readReply(reader)
+			if err != nil && !errors.As(err, new(RedisError)) {
+				return nil, err
+			}
+
+			elements = append(elements, element)
+		}
+
+		return elements, nil
+	}
+
+	return nil, fmt.Errorf("unknown redis reply type: %q", line[0])
+}
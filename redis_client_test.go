@@ -0,0 +1,211 @@
+package phpredishandler_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/paketo-buildpacks/php-redis-session-handler/fakes"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testRedisClient(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		server *fakes.RedisServer
+		config phpredishandler.RedisConfig
+	)
+
+	it.Before(func() {
+		var err error
+		server, err = fakes.NewRedisServer()
+		Expect(err).NotTo(HaveOccurred())
+
+		config = phpredishandler.RedisConfig{
+			Hostname: server.Hostname(),
+			Port:     server.Port(),
+		}
+	})
+
+	it.After(func() {
+		Expect(server.Close()).To(Succeed())
+	})
+
+	it("sends commands and decodes their replies", func() {
+		client, err := phpredishandler.DialRedis(config, time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		defer client.Close()
+
+		Expect(client.Do("PING")).To(Equal("PONG"))
+
+		Expect(client.Set("some-key", []byte("some-value"), time.Minute)).To(Succeed())
+		Expect(client.Do("GET", "some-key")).To(Equal([]byte("some-value")))
+		Expect(client.Do("GET", "missing-key")).To(BeNil())
+		Expect(client.Do("PTTL", "some-key")).To(BeNumerically(">", int64(0)))
+		Expect(client.Do("SCAN", "0", "MATCH", "some-*")).To(Equal([]interface{}{
+			[]byte("0"),
+			[]interface{}{[]byte("some-key")},
+		}))
+
+		_, err = client.Do("NOT-A-COMMAND")
+		Expect(err).To(MatchError(phpredishandler.RedisError("ERR unknown command 'NOT-A-COMMAND'")))
+
+		_, ttl, ok := server.Lookup("some-key")
+		Expect(ok).To(BeTrue())
+		Expect(ttl).To(BeNumerically("~", time.Minute, time.Second))
+	})
+
+	context("when a password is configured", func() {
+		it.Before(func() {
+			server.Password = "some-password"
+			config.Password = "some-password"
+		})
+
+		it("authenticates", func() {
+			client, err := phpredishandler.DialRedis(config, time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			Expect(client.Do("PING")).To(Equal("PONG"))
+			Expect(server.Commands()[0]).To(Equal([]string{"AUTH", "some-password"}))
+		})
+
+		context("when a database is configured", func() {
+			it.Before(func() {
+				config.Database = 2
+			})
+
+			it("selects it after authenticating", func() {
+				client, err := phpredishandler.DialRedis(config, time.Second)
+				Expect(err).NotTo(HaveOccurred())
+				defer client.Close()
+
+				Expect(client.Do("PING")).To(Equal("PONG"))
+				Expect(server.Commands()[:2]).To(Equal([][]string{
+					{"AUTH", "some-password"},
+					{"SELECT", "2"},
+				}))
+			})
+		})
+	})
+
+	context("when TLS is configured", func() {
+		var caFile string
+
+		it.Before(func() {
+			Expect(server.Close()).To(Succeed())
+
+			var err error
+			server, err = fakes.NewTLSRedisServer()
+			Expect(err).NotTo(HaveOccurred())
+
+			caFile = filepath.Join(t.TempDir(), "ca.pem")
+			Expect(os.WriteFile(caFile, server.Certificate(), 0600)).To(Succeed())
+
+			config = phpredishandler.RedisConfig{
+				Hostname:  server.Hostname(),
+				Port:      server.Port(),
+				TLS:       true,
+				TLSCAFile: caFile,
+			}
+		})
+
+		it("verifies the server with the CA bundle", func() {
+			client, err := phpredishandler.DialRedis(config, time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			Expect(client.Do("PING")).To(Equal("PONG"))
+		})
+
+		context("when peer verification is turned off", func() {
+			it.Before(func() {
+				verifyPeer := false
+				config.TLSVerifyPeer = &verifyPeer
+				config.TLSCAFile = ""
+			})
+
+			it("accepts the certificate", func() {
+				client, err := phpredishandler.DialRedis(config, time.Second)
+				Expect(err).NotTo(HaveOccurred())
+				defer client.Close()
+
+				Expect(client.Do("PING")).To(Equal("PONG"))
+			})
+		})
+
+		context("when the certificate is not signed by a trusted CA", func() {
+			it.Before(func() {
+				config.TLSCAFile = ""
+			})
+
+			it("returns an error", func() {
+				_, err := phpredishandler.DialRedis(config, time.Second)
+				Expect(err).To(MatchError(ContainSubstring("failed to connect to redis: tls: failed to verify certificate")))
+			})
+		})
+
+		context("when the CA bundle cannot be read", func() {
+			it.Before(func() {
+				config.TLSCAFile = filepath.Join(t.TempDir(), "missing.pem")
+			})
+
+			it("returns an error", func() {
+				_, err := phpredishandler.DialRedis(config, time.Second)
+				Expect(err).To(MatchError(ContainSubstring("failed to read the redis CA bundle")))
+			})
+		})
+
+		context("when the CA bundle contains no certificates", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(caFile, []byte("not a certificate"), 0600)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := phpredishandler.DialRedis(config, time.Second)
+				Expect(err).To(MatchError("the redis CA bundle " + caFile + " contains no certificates"))
+			})
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the server cannot be reached", func() {
+			it.Before(func() {
+				Expect(server.Close()).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := phpredishandler.DialRedis(config, time.Second)
+				Expect(err).To(MatchError(ContainSubstring("failed to connect to redis")))
+			})
+		})
+
+		context("when the password is wrong", func() {
+			it.Before(func() {
+				server.Password = "some-password"
+				config.Password = "some-other-password"
+			})
+
+			it("returns an error", func() {
+				_, err := phpredishandler.DialRedis(config, time.Second)
+				Expect(err).To(MatchError(ContainSubstring("failed to authenticate with redis: WRONGPASS")))
+			})
+		})
+
+		context("when the database cannot be selected", func() {
+			it.Before(func() {
+				config.Database = 16
+			})
+
+			it("returns an error", func() {
+				_, err := phpredishandler.DialRedis(config, time.Second)
+				Expect(err).To(MatchError("failed to select redis database 16: ERR DB index is out of range"))
+			})
+		})
+	})
+}
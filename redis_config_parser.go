@@ -1,6 +1,7 @@
 package phpredishandler
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -13,6 +14,72 @@ type RedisConfig struct {
 	Hostname string
 	Port     int
 	Password string
+	Prefix   string
+
+	// Client is the name of the SessionClient that handles sessions. An
+	// empty value selects phpredis.
+	Client string
+
+	// TLS connects to Hostname and Port over TLS. TLSVerifyPeer is nil
+	// unless peer verification is configured, and TLSCAFile is the CA
+	// bundle to verify the server with. Database selects the Redis
+	// database, 0 by default.
+	TLS           bool
+	TLSVerifyPeer *bool
+	TLSCAFile     string
+	Database      int
+
+	// LockingEnabled turns on phpredis session locking, so that concurrent
+	// requests cannot overwrite each other's session writes. LockExpire is
+	// in seconds, LockWaitTime in microseconds, and a LockRetries of -1
+	// retries forever. Zero values keep the phpredis defaults.
+	LockingEnabled bool
+	LockExpire     int
+	LockWaitTime   int
+	LockRetries    int
+
+	// GcMaxLifetime is the session.gc_maxlifetime in seconds, which is also
+	// the TTL of the session keys. LazyWrite is nil unless session.lazy_write
+	// is configured. EarlyRefresh makes phpredis refresh the TTL when it
+	// reads a session. Zero values keep the PHP and phpredis defaults.
+	GcMaxLifetime int
+	LazyWrite     *bool
+	EarlyRefresh  bool
+
+	// Serializer is the session.serialize_handler. Compression is the
+	// phpredis session compression, compressed at CompressionLevel when it is
+	// not zero. Empty values keep the PHP and phpredis defaults.
+	Serializer       string
+	Compression      string
+	CompressionLevel int
+
+	// SessionName and the cookie settings configure the session cookie. Nil
+	// and empty values keep the PHP defaults, unless Hardened fills them
+	// with secure values.
+	SessionName         string
+	CookieDomain        string
+	CookiePath          string
+	CookieSecure        *bool
+	CookieHttpOnly      *bool
+	CookieSameSite      string
+	UseStrictMode       *bool
+	SidLength           int
+	SidBitsPerCharacter int
+	Hardened            bool
+
+	// Sources records where each setting was read from, keyed by the
+	// setting name. Settings that were not configured map to SourceDefault.
+	Sources map[string]string
+}
+
+// SessionKeyPrefix returns the prefix phpredis uses for session keys, falling
+// back to the phpredis default when no prefix has been configured.
+func (c RedisConfig) SessionKeyPrefix() string {
+	if c.Prefix == "" {
+		return DefaultSessionPrefix
+	}
+
+	return c.Prefix
 }
 
 type RedisConfigParser struct {
@@ -31,6 +98,16 @@ func (p RedisConfigParser) Parse(dir string) (RedisConfig, error) {
 		return RedisConfig{}, err
 	}
 
+	sources := map[string]string{
+		"host":     SourceDefault,
+		"port":     SourceDefault,
+		"password": SourceDefault,
+		"prefix":   SourceDefault,
+	}
+	for _, setting := range sessionSettings {
+		sources[setting.Name] = SourceDefault
+	}
+
 	hostname := "127.0.0.1"
 	if hostFileExists {
 		hostnameBytes, err := os.ReadFile(hostFilepath)
@@ -39,6 +116,7 @@ func (p RedisConfigParser) Parse(dir string) (RedisConfig, error) {
 		}
 
 		hostname = strings.TrimSpace(string(hostnameBytes))
+		sources["host"] = hostFilepath
 	} else {
 		hostnameFileExists, err := fs.Exists(hostnameFilepath)
 		if err != nil {
@@ -53,6 +131,7 @@ func (p RedisConfigParser) Parse(dir string) (RedisConfig, error) {
 			}
 
 			hostname = strings.TrimSpace(string(hostnameBytes))
+			sources["host"] = hostnameFilepath
 		}
 	}
 
@@ -73,8 +152,9 @@ func (p RedisConfigParser) Parse(dir string) (RedisConfig, error) {
 
 		port, err = strconv.Atoi(strings.TrimSpace(string(portBytes)))
 		if err != nil {
-			return RedisConfig{}, err
+			return RedisConfig{}, fmt.Errorf("failed to parse %s: %w", portFilepath, err)
 		}
+		sources["port"] = portFilepath
 	}
 
 	passwordFilepath := filepath.Join(dir, "password")
@@ -93,11 +173,89 @@ func (p RedisConfigParser) Parse(dir string) (RedisConfig, error) {
 		}
 
 		password = strings.TrimSpace(string(passwordBytes))
+		sources["password"] = passwordFilepath
 	}
 
-	return RedisConfig{
+	prefix, prefixFileExists, err := readBindingFile(dir, "prefix")
+	if err != nil {
+		return RedisConfig{}, err
+	}
+
+	if prefixFileExists {
+		sources["prefix"] = filepath.Join(dir, "prefix")
+	}
+
+	config := RedisConfig{
 		Hostname: hostname,
 		Port:     port,
 		Password: password,
-	}, nil
+		Prefix:   prefix,
+		Sources:  sources,
+	}
+
+	for _, setting := range sessionSettings {
+		value, exists, err := readBindingFile(dir, setting.Name)
+		if err != nil {
+			return RedisConfig{}, err
+		}
+
+		if !exists {
+			continue
+		}
+
+		settingFilepath := filepath.Join(dir, setting.Name)
+		err = setting.apply(&config, value)
+		if err != nil {
+			return RedisConfig{}, fmt.Errorf("invalid %s %q in %s: %w", setting.Name, value, settingFilepath, err)
+		}
+		sources[setting.Name] = settingFilepath
+	}
+
+	err = validateRedisConfig(config)
+	if err != nil {
+		return RedisConfig{}, err
+	}
+
+	return config, nil
+}
+
+// validateRedisConfig rejects settings that would render an unusable
+// session.save_path.
+func validateRedisConfig(config RedisConfig) error {
+	if config.Hostname == "" {
+		return fmt.Errorf("invalid host in %s: must not be empty", config.Sources["host"])
+	}
+
+	if strings.ContainsAny(config.Hostname, " \t\r\n/?#&@") {
+		return fmt.Errorf("invalid host %q in %s: must be a hostname or IP address", config.Hostname, config.Sources["host"])
+	}
+
+	if config.Port < 1 || config.Port > 65535 {
+		return fmt.Errorf("invalid port %d in %s: must be between 1 and 65535", config.Port, config.Sources["port"])
+	}
+
+	return validateSessionSettings(config)
+}
+
+// readBindingFile returns the whitespace-trimmed contents of the named entry
+// in the binding directory, and whether that entry exists.
+func readBindingFile(dir, name string) (string, bool, error) {
+	path := filepath.Join(dir, name)
+
+	exists, err := fs.Exists(path)
+	if err != nil {
+		// untested
+		return "", false, err
+	}
+
+	if !exists {
+		return "", false, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, err
+	}
+
+	return strings.TrimSpace(string(content)), true, nil
 }
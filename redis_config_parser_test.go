@@ -1,6 +1,7 @@
 package phpredishandler_test
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -41,6 +42,37 @@ func testRedisConfigParser(t *testing.T, context spec.G, it spec.S) {
 			Hostname: "127.0.0.1",
 			Port:     6379,
 			Password: "",
+			Sources: map[string]string{
+				"host":                   "default",
+				"port":                   "default",
+				"password":               "default",
+				"prefix":                 "default",
+				"client":                 "default",
+				"locking_enabled":        "default",
+				"lock_expire":            "default",
+				"lock_wait_time":         "default",
+				"lock_retries":           "default",
+				"gc_maxlifetime":         "default",
+				"lazy_write":             "default",
+				"early_refresh":          "default",
+				"serializer":             "default",
+				"compression":            "default",
+				"compression_level":      "default",
+				"session_name":           "default",
+				"cookie_domain":          "default",
+				"cookie_path":            "default",
+				"cookie_secure":          "default",
+				"cookie_httponly":        "default",
+				"cookie_samesite":        "default",
+				"use_strict_mode":        "default",
+				"sid_length":             "default",
+				"sid_bits_per_character": "default",
+				"tls":                    "default",
+				"tls_verify_peer":        "default",
+				"tls_ca_file":            "default",
+				"database":               "default",
+				"hardened":               "default",
+			},
 		}))
 	})
 
@@ -54,6 +86,7 @@ func testRedisConfigParser(t *testing.T, context spec.G, it spec.S) {
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(config.Hostname).To(Equal("some-host"))
+			Expect(config.Sources).To(HaveKeyWithValue("host", filepath.Join(workingDir, "host")))
 		})
 
 		context("when there is whitespace", func() {
@@ -80,6 +113,7 @@ func testRedisConfigParser(t *testing.T, context spec.G, it spec.S) {
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(config.Hostname).To(Equal("some-other-host"))
+			Expect(config.Sources).To(HaveKeyWithValue("host", filepath.Join(workingDir, "hostname")))
 		})
 
 		context("when there is whitespace", func() {
@@ -106,6 +140,7 @@ func testRedisConfigParser(t *testing.T, context spec.G, it spec.S) {
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(config.Port).To(Equal(1234))
+			Expect(config.Sources).To(HaveKeyWithValue("port", filepath.Join(workingDir, "port")))
 		})
 
 		context("when there is whitespace", func() {
@@ -132,6 +167,7 @@ func testRedisConfigParser(t *testing.T, context spec.G, it spec.S) {
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(config.Password).To(Equal("some-password"))
+			Expect(config.Sources).To(HaveKeyWithValue("password", filepath.Join(workingDir, "password")))
 		})
 
 		context("when there is whitespace", func() {
@@ -148,6 +184,186 @@ func testRedisConfigParser(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
+	context("when the prefix file exists", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "prefix"), []byte("  some-prefix:\n"), os.ModePerm)).To(Succeed())
+		})
+
+		it("uses the value from the prefix file", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(config.Prefix).To(Equal("some-prefix:"))
+			Expect(config.SessionKeyPrefix()).To(Equal("some-prefix:"))
+			Expect(config.Sources).To(HaveKeyWithValue("prefix", filepath.Join(workingDir, "prefix")))
+		})
+	})
+
+	context("when the client file exists", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "client"), []byte("relay\n"), os.ModePerm)).To(Succeed())
+		})
+
+		it("uses the value from the client file", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(config.Client).To(Equal("relay"))
+			Expect(config.Sources).To(HaveKeyWithValue("client", filepath.Join(workingDir, "client")))
+		})
+	})
+
+	context("when the locking files exist", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "locking_enabled"), []byte("true\n"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "lock_expire"), []byte("30"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "lock_wait_time"), []byte("50000"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "lock_retries"), []byte("-1"), os.ModePerm)).To(Succeed())
+		})
+
+		it("uses the values from the locking files", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(config.LockingEnabled).To(BeTrue())
+			Expect(config.LockExpire).To(Equal(30))
+			Expect(config.LockWaitTime).To(Equal(50000))
+			Expect(config.LockRetries).To(Equal(-1))
+			Expect(config.Sources).To(HaveKeyWithValue("locking_enabled", filepath.Join(workingDir, "locking_enabled")))
+			Expect(config.Sources).To(HaveKeyWithValue("lock_retries", filepath.Join(workingDir, "lock_retries")))
+		})
+	})
+
+	context("when the lifetime files exist", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "gc_maxlifetime"), []byte("3600"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "lazy_write"), []byte("false"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "early_refresh"), []byte("true"), os.ModePerm)).To(Succeed())
+		})
+
+		it("uses the values from the lifetime files", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(config.GcMaxLifetime).To(Equal(3600))
+			Expect(config.LazyWrite).NotTo(BeNil())
+			Expect(*config.LazyWrite).To(BeFalse())
+			Expect(config.EarlyRefresh).To(BeTrue())
+			Expect(config.Sources).To(HaveKeyWithValue("gc_maxlifetime", filepath.Join(workingDir, "gc_maxlifetime")))
+		})
+	})
+
+	context("when the serializer and compression files exist", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "serializer"), []byte("igbinary"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "compression"), []byte("zstd"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "compression_level"), []byte("3"), os.ModePerm)).To(Succeed())
+		})
+
+		it("uses the values from the files", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(config.Serializer).To(Equal("igbinary"))
+			Expect(config.Compression).To(Equal("zstd"))
+			Expect(config.CompressionLevel).To(Equal(3))
+			Expect(config.Sources).To(HaveKeyWithValue("compression", filepath.Join(workingDir, "compression")))
+		})
+	})
+
+	context("when the cookie files exist", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "session_name"), []byte("APPSESSION"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "cookie_domain"), []byte("example.com"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "cookie_path"), []byte("/app"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "cookie_secure"), []byte("true"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "cookie_samesite"), []byte("strict"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "sid_length"), []byte("48"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "sid_bits_per_character"), []byte("6"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "hardened"), []byte("true"), os.ModePerm)).To(Succeed())
+		})
+
+		it("uses the values from the files", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(config.SessionName).To(Equal("APPSESSION"))
+			Expect(config.CookieDomain).To(Equal("example.com"))
+			Expect(config.CookiePath).To(Equal("/app"))
+			Expect(config.CookieSecure).NotTo(BeNil())
+			Expect(*config.CookieSecure).To(BeTrue())
+			Expect(config.CookieHttpOnly).To(BeNil())
+			Expect(config.CookieSameSite).To(Equal("Strict"))
+			Expect(config.SidLength).To(Equal(48))
+			Expect(config.SidBitsPerCharacter).To(Equal(6))
+			Expect(config.Hardened).To(BeTrue())
+			Expect(config.Sources).To(HaveKeyWithValue("cookie_samesite", filepath.Join(workingDir, "cookie_samesite")))
+		})
+	})
+
+	context("when the tls and database files exist", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "tls"), []byte("true"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "tls_verify_peer"), []byte("false"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "tls_ca_file"), []byte("/some/ca.pem"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "database"), []byte("2"), os.ModePerm)).To(Succeed())
+		})
+
+		it("uses the values from the files", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(config.TLS).To(BeTrue())
+			Expect(config.TLSVerifyPeer).NotTo(BeNil())
+			Expect(*config.TLSVerifyPeer).To(BeFalse())
+			Expect(config.TLSCAFile).To(Equal("/some/ca.pem"))
+			Expect(config.Database).To(Equal(2))
+			Expect(config.Sources).To(HaveKeyWithValue("database", filepath.Join(workingDir, "database")))
+		})
+
+		context("when tls is not enabled", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(workingDir, "tls"))).To(Succeed())
+			})
+
+			it("returns an error for the tls options", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(fmt.Sprintf("invalid tls_verify_peer in %s: requires tls", filepath.Join(workingDir, "tls_verify_peer"))))
+			})
+		})
+
+		context("when the database is negative", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "database"), []byte("-1"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring("must be a non-negative database number")))
+			})
+		})
+
+		context("when the CA file is not an absolute path", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "tls_ca_file"), []byte("ca.pem"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring("must be an absolute path")))
+			})
+		})
+	})
+
+	context("when the prefix file does not exist", func() {
+		it("uses the phpredis default session key prefix", func() {
+			config, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(config.SessionKeyPrefix()).To(Equal("PHPREDIS_SESSION:"))
+		})
+	})
+
 	context("failure cases", func() {
 		context("when there is an error determining if the files exist", func() {
 			it.Before(func() {
@@ -204,6 +420,195 @@ func testRedisConfigParser(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 
+		context("when the port is out of range", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "port"), []byte("65536"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(fmt.Sprintf("invalid port 65536 in %s: must be between 1 and 65535", filepath.Join(workingDir, "port"))))
+			})
+		})
+
+		context("when the client is unknown", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "client"), []byte("some-client"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring(`unknown session client "some-client"`)))
+			})
+		})
+
+		context("when locking_enabled is not a boolean", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "locking_enabled"), []byte("sometimes"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(fmt.Sprintf(`invalid locking_enabled "sometimes" in %s: must be a boolean`, filepath.Join(workingDir, "locking_enabled"))))
+			})
+		})
+
+		context("when lock_wait_time is not positive", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "lock_wait_time"), []byte("0"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring("must be a positive number of microseconds")))
+			})
+		})
+
+		context("when lock_retries is zero", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "lock_retries"), []byte("0"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring("must be -1 or a positive number of retries")))
+			})
+		})
+
+		context("when gc_maxlifetime is not positive", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "gc_maxlifetime"), []byte("-60"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(fmt.Sprintf(`invalid gc_maxlifetime "-60" in %s: must be a positive number of seconds`, filepath.Join(workingDir, "gc_maxlifetime"))))
+			})
+		})
+
+		context("when the serializer is unknown", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "serializer"), []byte("msgpack"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(fmt.Sprintf(`invalid serializer "msgpack" in %s: must be one of "php", "php_serialize" or "igbinary"`, filepath.Join(workingDir, "serializer"))))
+			})
+		})
+
+		context("when a compression level is set without a compression", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "compression_level"), []byte("3"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(fmt.Sprintf("invalid compression_level 3 in %s: requires a compression", filepath.Join(workingDir, "compression_level"))))
+			})
+		})
+
+		context("when the compression has no levels", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "compression"), []byte("lzf"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "compression_level"), []byte("3"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring("lzf compression has no levels")))
+			})
+		})
+
+		context("when the compression level is too high", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "compression"), []byte("lz4"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "compression_level"), []byte("13"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring("lz4 compression levels are between 1 and 12")))
+			})
+		})
+
+		context("when the session name is not alphanumeric", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "session_name"), []byte("my session"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(fmt.Sprintf(`invalid session_name "my session" in %s: must be alphanumeric and contain at least one letter`, filepath.Join(workingDir, "session_name"))))
+			})
+		})
+
+		context("when the cookie path is relative", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "cookie_path"), []byte("app"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring("must be a path starting with /")))
+			})
+		})
+
+		context("when cookie_samesite is unknown", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "cookie_samesite"), []byte("sometimes"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring(`must be one of "Strict", "Lax" or "None"`)))
+			})
+		})
+
+		context("when sid_bits_per_character is out of range", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "sid_bits_per_character"), []byte("8"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring("must be 4, 5 or 6")))
+			})
+		})
+
+		context("when the host file is empty", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "host"), []byte("\n"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(fmt.Sprintf("invalid host in %s: must not be empty", filepath.Join(workingDir, "host"))))
+			})
+		})
+
+		context("when the host is not a hostname", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "host"), []byte("user@some-host"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring(`invalid host "user@some-host"`)))
+			})
+		})
+
+		context("when there is an error reading the prefix file", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(workingDir, "prefix"), []byte("some-prefix"), 0000)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := parser.Parse(workingDir)
+				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+			})
+		})
+
 		context("when there is an error reading the password file", func() {
 			it.Before(func() {
 				Expect(os.WriteFile(filepath.Join(workingDir, "password"), []byte("some-password"), 0000)).To(Succeed())
@@ -2,16 +2,229 @@ package phpredishandler
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"text/template"
 
 	"github.com/paketo-buildpacks/packit/v2/scribe"
 )
 
+// secretPatterns match the password in a session save path, including the
+// auth[] user and password pairs of phpredis, and the quoted memcached SASL
+// password, which may contain escaped quotes.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`([?&]auth(?:\[\]|%5[Bb]%5[Dd])?=)[^&"\s]*`),
+	regexp.MustCompile(`(sess_sasl_password\s*=\s*")(?:[^"\\]|\\.)*`),
+}
+
+// RedactSecrets replaces the passwords in a rendered configuration so that it
+// can be safely printed.
+func RedactSecrets(contents string) string {
+	for _, pattern := range secretPatterns {
+		contents = pattern.ReplaceAllString(contents, "${1}REDACTED")
+	}
+
+	return contents
+}
+
+// redisConnection describes how the session handler reaches Redis. Scheme
+// is tcp or tls. VerifyPeer is empty unless peer verification is configured,
+// and 0 or 1 otherwise.
+type redisConnection struct {
+	Scheme     string
+	Host       string
+	Port       int
+	Password   string
+	Prefix     string
+	Database   int
+	VerifyPeer string
+	CAFile     string
+}
+
+// Address is the connection URL without credentials or options.
+func (c redisConnection) Address() string {
+	return fmt.Sprintf("%s://%s:%d", c.Scheme, c.Host, c.Port)
+}
+
+// phpRedisIniData is the data available to the php-redis.ini template.
+type phpRedisIniData struct {
+	SaveHandler string
+	SavePath    string
+	Extensions  []string
+	PrependFile string
+
+	// Client is the name of the session client and Connection the
+	// connection that SavePath was built from, so that templates can build
+	// their own save path with the savePath function.
+	Client     string
+	Connection redisConnection
+
+	LockingEnabled bool
+	LockExpire     int
+	LockWaitTime   int
+	LockRetries    int
+
+	GcMaxLifetime int
+	LazyWrite     string
+	EarlyRefresh  bool
+
+	Serializer       string
+	Compression      string
+	CompressionLevel int
+
+	SessionName         string
+	CookieDomain        string
+	CookiePath          string
+	CookieSecure        string
+	CookieHttpOnly      string
+	CookieSameSite      string
+	UseStrictMode       string
+	SidLength           int
+	SidBitsPerCharacter int
+}
+
+// Loads reports whether the extension is loaded by an extension= line, for
+// example {{if .Loads "igbinary"}}.
+func (d phpRedisIniData) Loads(name string) bool {
+	for _, extension := range d.Extensions {
+		if strings.TrimSuffix(filepath.Base(extension), ".so") == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// phpRedisIniFuncs are the functions available to the php-redis.ini template.
+var phpRedisIniFuncs = template.FuncMap{
+	"savePath": savePath,
+	"quote":    iniQuote,
+}
+
+// savePath renders the session.save_path of the connection. The params are
+// additional key and value pairs for the query string, for example
+// {{savePath .Connection "database" "2" "timeout" "2.5"}}.
+func savePath(connection redisConnection, params ...string) (string, error) {
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("savePath requires pairs of query parameters, got %d values", len(params))
+	}
+
+	path := connection.Address()
+
+	query := url.Values{}
+	if connection.Password != "" {
+		query.Set("auth", connection.Password)
+	}
+
+	if connection.Prefix != "" {
+		query.Set("prefix", connection.Prefix)
+	}
+
+	if connection.Database != 0 {
+		query.Set("database", strconv.Itoa(connection.Database))
+	}
+
+	if connection.VerifyPeer != "" {
+		query.Set("stream[verify_peer]", connection.VerifyPeer)
+	}
+
+	if connection.CAFile != "" {
+		query.Set("stream[cafile]", connection.CAFile)
+	}
+
+	for i := 0; i < len(params); i += 2 {
+		query.Set(params[i], params[i+1])
+	}
+
+	if len(query) > 0 {
+		path = fmt.Sprintf("%s?%s", path, query.Encode())
+	}
+
+	return path, nil
+}
+
+// iniQuote renders a string as a double-quoted ini value. A $ is escaped as
+// well, as PHP expands ${NAME} in double-quoted values to the environment
+// variable NAME.
+func iniQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`).Replace(value) + `"`
+}
+
+// RedisIniTemplate returns the php-redis.ini template to render: the
+// CustomRedisIniTemplate in the application's working directory when it
+// exists, and the template shipped in the buildpack otherwise.
+func RedisIniTemplate(workingDir, cnbPath string) (string, error) {
+	path := filepath.Join(workingDir, CustomRedisIniTemplate)
+	_, err := os.Stat(path)
+	if err == nil {
+		return path, nil
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	return filepath.Join(cnbPath, "config", "php-redis.ini"), nil
+}
+
+// fpmPoolData is the data available to the php-fpm pool template.
+type fpmPoolData struct {
+	Pool        string
+	SaveHandler string
+	SavePath    string
+}
+
+// predisHandlerData is the data available to the Predis session handler
+// template. Values are rendered as PHP literals with the php template
+// function.
+type predisHandlerData struct {
+	Autoload   string
+	Parameters phpArray
+	Prefix     string
+}
+
+type phpArrayEntry struct {
+	Key   string
+	Value interface{}
+}
+
+// phpArray is an associative PHP array that keeps its keys in order.
+type phpArray []phpArrayEntry
+
+// phpLiteral renders a string, int, bool or phpArray as a PHP literal.
+func phpLiteral(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'", nil
+	case int:
+		return strconv.Itoa(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case phpArray:
+		var entries []string
+		for _, entry := range v {
+			literal, err := phpLiteral(entry.Value)
+			if err != nil {
+				return "", err
+			}
+
+			key, _ := phpLiteral(entry.Key)
+			entries = append(entries, fmt.Sprintf("%s => %s", key, literal))
+		}
+
+		return "[" + strings.Join(entries, ", ") + "]", nil
+	}
+
+	return "", fmt.Errorf("cannot render %T as a PHP literal", value)
+}
+
 type RedisConfigWriter struct {
 	logger scribe.Emitter
 }
@@ -22,29 +235,224 @@ func NewRedisConfigWriter(logger scribe.Emitter) RedisConfigWriter {
 	}
 }
 
-func (c RedisConfigWriter) Write(redisConfig RedisConfig, layerPath, cnbPath string) (string, error) {
-	tmpl, err := template.New("php-redis.ini").ParseFiles(filepath.Join(cnbPath, "config", "php-redis.ini"))
+// Write renders the php-redis.ini template at templatePath into the layer. The
+// extensions are the values of the extension= lines the file needs to load.
+func (c RedisConfigWriter) Write(redisConfig RedisConfig, extensions []string, layerPath, templatePath string) (string, error) {
+	tmpl, err := template.New(filepath.Base(templatePath)).Funcs(phpRedisIniFuncs).ParseFiles(templatePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse PHP redis config template: %w", err)
 	}
 
-	sessionSavePath := fmt.Sprintf("tcp://%s:%d", redisConfig.Hostname, redisConfig.Port)
-	c.logger.Debug.Subprocess("Including session save path: %s", sessionSavePath)
+	client, err := LookupSessionClient(redisConfig.Client)
+	if err != nil {
+		return "", err
+	}
+	c.logger.Debug.Subprocess("Using the %s session client", client.Name)
+
+	connection := connectionOf(redisConfig)
+	c.logger.Debug.Subprocess("Including session save path: %s", connection.Address())
 
 	if redisConfig.Password != "" {
-		sessionSavePath = fmt.Sprintf("%s?auth=%s", sessionSavePath, url.QueryEscape(redisConfig.Password))
 		c.logger.Debug.Subprocess("Including a password on the session save path")
 	}
 
-	// Configuration set by this buildpack
+	if redisConfig.Prefix != "" {
+		c.logger.Debug.Subprocess("Including session key prefix: %s", redisConfig.Prefix)
+	}
+
+	if redisConfig.Database != 0 {
+		c.logger.Debug.Subprocess("Including session database: %d", redisConfig.Database)
+	}
+
+	sessionSavePath, err := savePath(connection)
+	if err != nil {
+		// not tested
+		return "", err
+	}
+
+	data := phpRedisIniData{
+		SaveHandler: client.SaveHandler,
+		SavePath:    sessionSavePath,
+		Extensions:  extensions,
+		Client:      client.Name,
+		Connection:  connection,
+	}
+
+	if client.PurePHP() {
+		data.PrependFile = filepath.Join(layerPath, PredisHandlerFile)
+		c.logger.Debug.Subprocess("Registering the session handler from: %s", data.PrependFile)
+	}
+
+	if redisConfig.LockingEnabled {
+		if client.Name != SessionClientPhpRedis {
+			return "", fmt.Errorf("session locking is only supported by the %s session client, not %s", SessionClientPhpRedis, client.Name)
+		}
+
+		data.LockingEnabled = true
+		data.LockExpire = redisConfig.LockExpire
+		data.LockWaitTime = redisConfig.LockWaitTime
+		data.LockRetries = redisConfig.LockRetries
+		c.logger.Debug.Subprocess("Enabling session locking")
+	}
+
+	if redisConfig.EarlyRefresh && client.Name != SessionClientPhpRedis {
+		return "", fmt.Errorf("early session refresh is only supported by the %s session client, not %s", SessionClientPhpRedis, client.Name)
+	}
+
+	if redisConfig.Compression != "" && client.Name != SessionClientPhpRedis {
+		return "", fmt.Errorf("session compression is only supported by the %s session client, not %s", SessionClientPhpRedis, client.Name)
+	}
+
+	data.Serializer = redisConfig.Serializer
+	data.Compression = redisConfig.Compression
+	data.CompressionLevel = redisConfig.CompressionLevel
+	data.GcMaxLifetime = redisConfig.GcMaxLifetime
+	data.EarlyRefresh = redisConfig.EarlyRefresh
+	data.LazyWrite = iniBool(redisConfig.LazyWrite)
+
+	cookieConfig := redisConfig.WithHardening()
+	data.SessionName = cookieConfig.SessionCookieName()
+	data.CookieDomain = cookieConfig.CookieDomain
+	data.CookiePath = cookieConfig.CookiePath
+	data.CookieSecure = iniBool(cookieConfig.CookieSecure)
+	data.CookieHttpOnly = iniBool(cookieConfig.CookieHttpOnly)
+	data.CookieSameSite = cookieConfig.CookieSameSite
+	data.UseStrictMode = iniBool(cookieConfig.UseStrictMode)
+	data.SidLength = cookieConfig.SidLength
+	data.SidBitsPerCharacter = cookieConfig.SidBitsPerCharacter
+	if cookieConfig.Hardened {
+		c.logger.Debug.Subprocess("Applying the hardened session cookie preset")
+	}
+
+	return writeTemplate(tmpl, data, filepath.Join(layerPath, "php-redis.ini"))
+}
+
+// WriteFpmPool renders a php-fpm pool configuration into dir that sets the
+// session handler and save path with php_admin_value, so that neither the pool
+// nor the application can change them. The save path holds the password, so
+// dir is the layer rather than the application directory.
+func (c RedisConfigWriter) WriteFpmPool(redisConfig RedisConfig, pool, dir, cnbPath string) (string, error) {
+	tmpl, err := template.New(FpmPoolTemplate).ParseFiles(filepath.Join(cnbPath, "config", FpmPoolTemplate))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse php-fpm pool template: %w", err)
+	}
+
+	client, err := LookupSessionClient(redisConfig.Client)
+	if err != nil {
+		return "", err
+	}
+
+	if client.PurePHP() {
+		return "", fmt.Errorf("the %s session client registers its session handler at runtime, so it cannot be locked in the php-fpm pool", client.Name)
+	}
+
+	sessionSavePath, err := savePath(connectionOf(redisConfig))
+	if err != nil {
+		// not tested
+		return "", err
+	}
+
+	err = os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+
+	return writeTemplate(tmpl, fpmPoolData{
+		Pool:        pool,
+		SaveHandler: client.SaveHandler,
+		SavePath:    sessionSavePath,
+	}, filepath.Join(dir, FpmPoolFile))
+}
+
+// connectionOf returns the connection the session handler uses to reach the
+// Redis server of the config.
+func connectionOf(redisConfig RedisConfig) redisConnection {
+	connection := redisConnection{
+		Scheme:   "tcp",
+		Host:     redisConfig.Hostname,
+		Port:     redisConfig.Port,
+		Password: redisConfig.Password,
+		Prefix:   redisConfig.Prefix,
+		Database: redisConfig.Database,
+	}
+
+	if redisConfig.TLS {
+		connection.Scheme = "tls"
+		connection.VerifyPeer = iniFlag(redisConfig.TLSVerifyPeer)
+		connection.CAFile = redisConfig.TLSCAFile
+	}
+
+	return connection
+}
+
+// iniFlag renders an optional boolean as 0 or 1, and nil as an empty string.
+func iniFlag(b *bool) string {
+	if b == nil {
+		return ""
+	}
+
+	if *b {
+		return "1"
+	}
+
+	return "0"
+}
+
+// WritePredisHandler renders the auto_prepend_file that registers a Predis
+// session handler for the predis session client. The predisPath is the
+// directory containing the Predis autoload.php.
+func (c RedisConfigWriter) WritePredisHandler(redisConfig RedisConfig, predisPath, layerPath, cnbPath string) (string, error) {
+	tmpl, err := template.New(PredisHandlerFile).Funcs(template.FuncMap{"php": phpLiteral}).ParseFiles(filepath.Join(cnbPath, "config", PredisHandlerFile))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse Predis session handler template: %w", err)
+	}
+
+	parameters := phpArray{
+		{Key: "scheme", Value: "tcp"},
+		{Key: "host", Value: redisConfig.Hostname},
+		{Key: "port", Value: redisConfig.Port},
+	}
+	if redisConfig.TLS {
+		parameters[0].Value = "tls"
+
+		var ssl phpArray
+		if redisConfig.TLSVerifyPeer != nil {
+			ssl = append(ssl, phpArrayEntry{Key: "verify_peer", Value: *redisConfig.TLSVerifyPeer})
+		}
+
+		if redisConfig.TLSCAFile != "" {
+			ssl = append(ssl, phpArrayEntry{Key: "cafile", Value: redisConfig.TLSCAFile})
+		}
+
+		if len(ssl) > 0 {
+			parameters = append(parameters, phpArrayEntry{Key: "ssl", Value: ssl})
+		}
+	}
+
+	if redisConfig.Password != "" {
+		parameters = append(parameters, phpArrayEntry{Key: "password", Value: redisConfig.Password})
+	}
+
+	if redisConfig.Database != 0 {
+		parameters = append(parameters, phpArrayEntry{Key: "database", Value: redisConfig.Database})
+	}
+
+	return writeTemplate(tmpl, predisHandlerData{
+		Autoload:   filepath.Join(predisPath, "autoload.php"),
+		Parameters: parameters,
+		Prefix:     redisConfig.SessionKeyPrefix(),
+	}, filepath.Join(layerPath, PredisHandlerFile))
+}
+
+func writeTemplate(tmpl *template.Template, data interface{}, path string) (string, error) {
 	var b bytes.Buffer
-	err = tmpl.Execute(&b, sessionSavePath)
+	err := tmpl.Execute(&b, data)
 	if err != nil {
 		// not tested
 		return "", err
 	}
 
-	f, err := os.OpenFile(filepath.Join(layerPath, "php-redis.ini"), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.ModePerm)
 	if err != nil {
 		return "", err
 	}
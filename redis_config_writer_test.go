@@ -2,6 +2,7 @@ package phpredishandler_test
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -19,7 +20,9 @@ func testRedisConfigWriter(t *testing.T, context spec.G, it spec.S) {
 
 		layerDir          string
 		cnbDir            string
+		templatePath      string
 		redisConfig       phpredishandler.RedisConfig
+		extensions        []string
 		redisConfigWriter phpredishandler.RedisConfigWriter
 	)
 
@@ -33,13 +36,16 @@ func testRedisConfigWriter(t *testing.T, context spec.G, it spec.S) {
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(os.MkdirAll(filepath.Join(cnbDir, "config"), os.ModePerm)).To(Succeed())
-		Expect(os.WriteFile(filepath.Join(cnbDir, "config", "php-redis.ini"), []byte(`session.save_path = "{{.}}"`), os.ModePerm)).To(Succeed())
+		templatePath = filepath.Join(cnbDir, "config", "php-redis.ini")
+		Expect(os.WriteFile(filepath.Join(cnbDir, "config", "php-redis.ini"), []byte("{{range .Extensions}}extension={{.}}\n{{end}}session.save_handler = {{.SaveHandler}}\nsession.save_path = \"{{.SavePath}}\""), os.ModePerm)).To(Succeed())
 
 		redisConfig = phpredishandler.RedisConfig{
 			Hostname: "some-hostname",
 			Port:     1234,
 			Password: "some-password",
 		}
+		extensions = []string{"redis.so", "igbinary.so"}
+
 		logEmitter := scribe.NewEmitter(bytes.NewBuffer(nil))
 		redisConfigWriter = phpredishandler.NewRedisConfigWriter(logEmitter)
 	})
@@ -50,7 +56,7 @@ func testRedisConfigWriter(t *testing.T, context spec.G, it spec.S) {
 	})
 
 	it("writes a redis config ini file into the redis config layer", func() {
-		redisConfigFilePath, err := redisConfigWriter.Write(redisConfig, layerDir, cnbDir)
+		redisConfigFilePath, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, templatePath)
 		Expect(err).NotTo(HaveOccurred())
 
 		Expect(redisConfigFilePath).To(Equal(filepath.Join(layerDir, "php-redis.ini")))
@@ -58,7 +64,23 @@ func testRedisConfigWriter(t *testing.T, context spec.G, it spec.S) {
 		contents, err := os.ReadFile(redisConfigFilePath)
 		Expect(err).NotTo(HaveOccurred())
 
-		Expect(string(contents)).To(ContainSubstring(`session.save_path = "tcp://some-hostname:1234?auth=some-password"`))
+		Expect(string(contents)).To(Equal("extension=redis.so\nextension=igbinary.so\nsession.save_handler = redis\nsession.save_path = \"tcp://some-hostname:1234?auth=some-password\""))
+	})
+
+	context("when no extensions need to be loaded", func() {
+		it.Before(func() {
+			extensions = nil
+		})
+
+		it("writes no extension lines", func() {
+			redisConfigFilePath, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, templatePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(redisConfigFilePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(contents)).To(Equal("session.save_handler = redis\nsession.save_path = \"tcp://some-hostname:1234?auth=some-password\""))
+		})
 	})
 
 	context("when there is no password", func() {
@@ -67,7 +89,7 @@ func testRedisConfigWriter(t *testing.T, context spec.G, it spec.S) {
 		})
 
 		it("writes a redis config ini file into the redis config layer without a password", func() {
-			redisConfigFilePath, err := redisConfigWriter.Write(redisConfig, layerDir, cnbDir)
+			redisConfigFilePath, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, templatePath)
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(redisConfigFilePath).To(Equal(filepath.Join(layerDir, "php-redis.ini")))
@@ -79,6 +101,612 @@ func testRedisConfigWriter(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
+	context("when the client is relay", func() {
+		it.Before(func() {
+			redisConfig.Client = "relay"
+		})
+
+		it("uses the relay save handler", func() {
+			redisConfigFilePath, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, templatePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(redisConfigFilePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(contents)).To(ContainSubstring("session.save_handler = relay\n"))
+		})
+	})
+
+	context("when the client is predis", func() {
+		it.Before(func() {
+			redisConfig.Client = "predis"
+			extensions = nil
+			Expect(os.WriteFile(filepath.Join(cnbDir, "config", "php-redis.ini"), []byte(`{{if .PrependFile}}auto_prepend_file = "{{.PrependFile}}"{{end}}{{if .SaveHandler}}session.save_handler = {{.SaveHandler}}{{end}}`), os.ModePerm)).To(Succeed())
+		})
+
+		it("prepends the Predis session handler instead of setting a save handler", func() {
+			redisConfigFilePath, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, templatePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(redisConfigFilePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(contents)).To(Equal(fmt.Sprintf(`auto_prepend_file = "%s"`, filepath.Join(layerDir, "predis-session-handler.php"))))
+		})
+	})
+
+	context("when session locking is enabled", func() {
+		it.Before(func() {
+			redisConfig.LockingEnabled = true
+			redisConfig.LockWaitTime = 50000
+			redisConfig.LockRetries = -1
+			Expect(os.WriteFile(filepath.Join(cnbDir, "config", "php-redis.ini"), []byte(`{{if .LockingEnabled}}locking_enabled = 1
+{{if .LockExpire}}lock_expire = {{.LockExpire}}
+{{end}}{{if .LockWaitTime}}lock_wait_time = {{.LockWaitTime}}
+{{end}}{{if .LockRetries}}lock_retries = {{.LockRetries}}
+{{end}}{{end}}`), os.ModePerm)).To(Succeed())
+		})
+
+		it("writes the configured locking settings", func() {
+			redisConfigFilePath, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, templatePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(redisConfigFilePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(contents)).To(Equal("locking_enabled = 1\nlock_wait_time = 50000\nlock_retries = -1\n"))
+		})
+
+		context("when the client is not phpredis", func() {
+			it.Before(func() {
+				redisConfig.Client = "relay"
+			})
+
+			it("returns an error", func() {
+				_, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, templatePath)
+				Expect(err).To(MatchError("session locking is only supported by the phpredis session client, not relay"))
+			})
+		})
+	})
+
+	context("when the session lifetime is configured", func() {
+		it.Before(func() {
+			lazyWrite := false
+			redisConfig.GcMaxLifetime = 3600
+			redisConfig.LazyWrite = &lazyWrite
+			redisConfig.EarlyRefresh = true
+			Expect(os.WriteFile(filepath.Join(cnbDir, "config", "php-redis.ini"), []byte(`{{if .GcMaxLifetime}}session.gc_maxlifetime = {{.GcMaxLifetime}}
+{{end}}{{if .LazyWrite}}session.lazy_write = {{.LazyWrite}}
+{{end}}{{if .EarlyRefresh}}redis.session.early_refresh = 1
+{{end}}`), os.ModePerm)).To(Succeed())
+		})
+
+		it("writes the lifetime settings", func() {
+			redisConfigFilePath, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, templatePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(redisConfigFilePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(contents)).To(Equal("session.gc_maxlifetime = 3600\nsession.lazy_write = Off\nredis.session.early_refresh = 1\n"))
+		})
+
+		context("when the client is predis", func() {
+			it.Before(func() {
+				redisConfig.Client = "predis"
+			})
+
+			it("returns an error for early refresh", func() {
+				_, err := redisConfigWriter.Write(redisConfig, nil, layerDir, templatePath)
+				Expect(err).To(MatchError("early session refresh is only supported by the phpredis session client, not predis"))
+			})
+		})
+	})
+
+	context("when the serializer and compression are configured", func() {
+		it.Before(func() {
+			redisConfig.Serializer = "igbinary"
+			redisConfig.Compression = "zstd"
+			redisConfig.CompressionLevel = 3
+			Expect(os.WriteFile(filepath.Join(cnbDir, "config", "php-redis.ini"), []byte(`{{if .Serializer}}session.serialize_handler = {{.Serializer}}
+{{end}}{{if .Compression}}redis.session.compression = {{.Compression}}
+{{if .CompressionLevel}}redis.session.compression_level = {{.CompressionLevel}}
+{{end}}{{end}}`), os.ModePerm)).To(Succeed())
+		})
+
+		it("writes the serializer and compression settings", func() {
+			redisConfigFilePath, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, templatePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(redisConfigFilePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(contents)).To(Equal("session.serialize_handler = igbinary\nredis.session.compression = zstd\nredis.session.compression_level = 3\n"))
+		})
+
+		context("when the client is not phpredis", func() {
+			it.Before(func() {
+				redisConfig.Client = "relay"
+			})
+
+			it("returns an error", func() {
+				_, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, templatePath)
+				Expect(err).To(MatchError("session compression is only supported by the phpredis session client, not relay"))
+			})
+		})
+	})
+
+	context("when the session cookie is configured", func() {
+		it.Before(func() {
+			secure := false
+			redisConfig.SessionName = "APPSESSION"
+			redisConfig.CookiePath = "/app"
+			redisConfig.CookieSecure = &secure
+			redisConfig.Hardened = true
+			Expect(os.WriteFile(filepath.Join(cnbDir, "config", "php-redis.ini"), []byte(`session.name = {{.SessionName}}
+{{if .CookieDomain}}session.cookie_domain = "{{.CookieDomain}}"
+{{end}}{{if .CookiePath}}session.cookie_path = "{{.CookiePath}}"
+{{end}}{{if .CookieSecure}}session.cookie_secure = {{.CookieSecure}}
+{{end}}{{if .CookieHttpOnly}}session.cookie_httponly = {{.CookieHttpOnly}}
+{{end}}{{if .CookieSameSite}}session.cookie_samesite = "{{.CookieSameSite}}"
+{{end}}{{if .UseStrictMode}}session.use_strict_mode = {{.UseStrictMode}}
+{{end}}`), os.ModePerm)).To(Succeed())
+		})
+
+		it("writes the cookie settings with the hardened preset filling the others", func() {
+			redisConfigFilePath, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, templatePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(redisConfigFilePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(contents)).To(Equal(`session.name = APPSESSION
+session.cookie_path = "/app"
+session.cookie_secure = Off
+session.cookie_httponly = On
+session.cookie_samesite = "Lax"
+session.use_strict_mode = On
+`))
+		})
+	})
+
+	context("WriteFpmPool", func() {
+		var poolDir string
+
+		it.Before(func() {
+			poolDir = filepath.Join(layerDir, ".php.fpm.d")
+			Expect(os.WriteFile(filepath.Join(cnbDir, "config", "php-fpm-pool.conf"), []byte(`[{{.Pool}}]
+php_admin_value[session.save_handler] = {{.SaveHandler}}
+php_admin_value[session.save_path] = "{{.SavePath}}"
+`), os.ModePerm)).To(Succeed())
+		})
+
+		it("locks the session handler and save path in the pool", func() {
+			poolPath, err := redisConfigWriter.WriteFpmPool(redisConfig, "www", poolDir, cnbDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(poolPath).To(Equal(filepath.Join(poolDir, "php-redis-session.conf")))
+
+			contents, err := os.ReadFile(poolPath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(contents)).To(Equal(`[www]
+php_admin_value[session.save_handler] = redis
+php_admin_value[session.save_path] = "tcp://some-hostname:1234?auth=some-password"
+`))
+		})
+
+		context("when the client registers its handler at runtime", func() {
+			it.Before(func() {
+				redisConfig.Client = "predis"
+			})
+
+			it("returns an error", func() {
+				_, err := redisConfigWriter.WriteFpmPool(redisConfig, "www", poolDir, cnbDir)
+				Expect(err).To(MatchError("the predis session client registers its session handler at runtime, so it cannot be locked in the php-fpm pool"))
+			})
+		})
+
+		context("when the template is not parseable", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cnbDir, "config", "php-fpm-pool.conf"), []byte(`{{.`), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := redisConfigWriter.WriteFpmPool(redisConfig, "www", poolDir, cnbDir)
+				Expect(err).To(MatchError(ContainSubstring("failed to parse php-fpm pool template")))
+			})
+		})
+	})
+
+	context("WritePredisHandler", func() {
+		it.Before(func() {
+			redisConfig.Password = `some-'pass\word`
+			Expect(os.WriteFile(filepath.Join(cnbDir, "config", "predis-session-handler.php"), []byte(`require {{php .Autoload}}; {{php .Parameters}}; {{php .Prefix}};`), os.ModePerm)).To(Succeed())
+		})
+
+		it("renders the connection settings as PHP literals", func() {
+			handlerPath, err := redisConfigWriter.WritePredisHandler(redisConfig, "/some/predis", layerDir, cnbDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(handlerPath).To(Equal(filepath.Join(layerDir, "predis-session-handler.php")))
+
+			contents, err := os.ReadFile(handlerPath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(contents)).To(Equal(`require '/some/predis/autoload.php'; ['scheme' => 'tcp', 'host' => 'some-hostname', 'port' => 1234, 'password' => 'some-\'pass\\word']; 'PHPREDIS_SESSION:';`))
+		})
+
+		context("when TLS and a database are configured", func() {
+			it.Before(func() {
+				verifyPeer := false
+				redisConfig.Password = ""
+				redisConfig.TLS = true
+				redisConfig.TLSVerifyPeer = &verifyPeer
+				redisConfig.TLSCAFile = "/some/ca.pem"
+				redisConfig.Database = 2
+			})
+
+			it("connects over tls to the database", func() {
+				handlerPath, err := redisConfigWriter.WritePredisHandler(redisConfig, "/some/predis", layerDir, cnbDir)
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := os.ReadFile(handlerPath)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(string(contents)).To(Equal(`require '/some/predis/autoload.php'; ['scheme' => 'tls', 'host' => 'some-hostname', 'port' => 1234, 'ssl' => ['verify_peer' => false, 'cafile' => '/some/ca.pem'], 'database' => 2]; 'PHPREDIS_SESSION:';`))
+			})
+		})
+
+		context("when the template is not parseable", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(cnbDir, "config", "predis-session-handler.php"), []byte(`{{.`), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := redisConfigWriter.WritePredisHandler(redisConfig, "/some/predis", layerDir, cnbDir)
+				Expect(err).To(MatchError(ContainSubstring("failed to parse Predis session handler template")))
+			})
+		})
+	})
+
+	context("when there is a prefix", func() {
+		it.Before(func() {
+			redisConfig.Prefix = "some-prefix:"
+		})
+
+		it("includes the prefix on the session save path", func() {
+			redisConfigFilePath, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, templatePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(redisConfigFilePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(contents)).To(ContainSubstring(`session.save_path = "tcp://some-hostname:1234?auth=some-password&prefix=some-prefix%3A"`))
+		})
+	})
+
+	context("when TLS and a database are configured", func() {
+		it.Before(func() {
+			verifyPeer := false
+			redisConfig.TLS = true
+			redisConfig.TLSVerifyPeer = &verifyPeer
+			redisConfig.TLSCAFile = "/some/ca.pem"
+			redisConfig.Database = 2
+		})
+
+		it("connects over tls to the database", func() {
+			redisConfigFilePath, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, templatePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(redisConfigFilePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(contents)).To(ContainSubstring(`session.save_path = "tls://some-hostname:1234?auth=some-password&database=2&stream%5Bcafile%5D=%2Fsome%2Fca.pem&stream%5Bverify_peer%5D=0"`))
+		})
+	})
+
+	context("RedactSecrets", func() {
+		it("replaces passwords in session save paths", func() {
+			Expect(phpredishandler.RedactSecrets(`session.save_path = "tcp://some-host:1234?auth=some%2Bpassword&prefix=some-prefix"`)).To(Equal(
+				`session.save_path = "tcp://some-host:1234?auth=REDACTED&prefix=some-prefix"`,
+			))
+			Expect(phpredishandler.RedactSecrets(`session.save_path = "tcp://some-host:1234?auth[]=some-user&auth[]=some-password"`)).To(Equal(
+				`session.save_path = "tcp://some-host:1234?auth[]=REDACTED&auth[]=REDACTED"`,
+			))
+			Expect(phpredishandler.RedactSecrets(`session.save_path = "tcp://some-host:1234"`)).To(Equal(
+				`session.save_path = "tcp://some-host:1234"`,
+			))
+		})
+	})
+
+	context("when the template composes its own settings", func() {
+		it.Before(func() {
+			redisConfig.Prefix = "some-prefix"
+			templatePath = filepath.Join(cnbDir, "php-redis.ini.tmpl")
+			Expect(os.WriteFile(templatePath, []byte(`client = {{.Client}}
+host = {{.Connection.Host}}:{{.Connection.Port}}
+session.save_path = {{quote (savePath .Connection "database" "2" "timeout" "2.5")}}
+{{if .Loads "igbinary"}}igbinary = yes
+{{end}}{{if .Loads "msgpack"}}msgpack = yes
+{{end}}`), os.ModePerm)).To(Succeed())
+		})
+
+		it("renders the template with the connection and the helper functions", func() {
+			redisConfigFilePath, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, templatePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(redisConfigFilePath).To(Equal(filepath.Join(layerDir, "php-redis.ini")))
+
+			contents, err := os.ReadFile(redisConfigFilePath)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(string(contents)).To(Equal(`client = phpredis
+host = some-hostname:1234
+session.save_path = "tcp://some-hostname:1234?auth=some-password&database=2&prefix=some-prefix&timeout=2.5"
+igbinary = yes
+`))
+		})
+
+		context("when savePath receives an odd number of parameters", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(templatePath, []byte(`{{savePath .Connection "database"}}`), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, templatePath)
+				Expect(err).To(MatchError(ContainSubstring("savePath requires pairs of query parameters, got 1 values")))
+			})
+		})
+	})
+
+	context("RedisIniTemplate", func() {
+		var workingDir string
+
+		it.Before(func() {
+			var err error
+			workingDir, err = os.MkdirTemp("", "working-dir")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(workingDir)).To(Succeed())
+		})
+
+		it("returns the template shipped in the buildpack", func() {
+			path, err := phpredishandler.RedisIniTemplate(workingDir, cnbDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(cnbDir, "config", "php-redis.ini")))
+		})
+
+		context("when the application provides a template", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(workingDir, ".php-redis-session"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, ".php-redis-session", "php-redis.ini.tmpl"), nil, os.ModePerm)).To(Succeed())
+			})
+
+			it("returns the application's template", func() {
+				path, err := phpredishandler.RedisIniTemplate(workingDir, cnbDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(path).To(Equal(filepath.Join(workingDir, ".php-redis-session", "php-redis.ini.tmpl")))
+			})
+		})
+
+		context("when the template cannot be stat'd", func() {
+			it.Before(func() {
+				Expect(os.Chmod(workingDir, 0000)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Chmod(workingDir, os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := phpredishandler.RedisIniTemplate(workingDir, cnbDir)
+				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+			})
+		})
+	})
+
+	context("with the shipped templates", func() {
+		var buildpackDir string
+
+		it.Before(func() {
+			var err error
+			buildpackDir, err = filepath.Abs(".")
+			Expect(err).NotTo(HaveOccurred())
+
+			verifyPeer := false
+			enabled := true
+			lazyWrite := false
+			redisConfig = phpredishandler.RedisConfig{
+				Hostname:            "redis.example.com",
+				Port:                6380,
+				Password:            `some "pass"word$`,
+				Prefix:              "app:",
+				TLS:                 true,
+				TLSVerifyPeer:       &verifyPeer,
+				TLSCAFile:           "/etc/ssl/redis-ca.pem",
+				Database:            2,
+				LockingEnabled:      true,
+				LockExpire:          30,
+				LockWaitTime:        20000,
+				LockRetries:         50,
+				GcMaxLifetime:       3600,
+				LazyWrite:           &lazyWrite,
+				EarlyRefresh:        true,
+				Serializer:          "igbinary",
+				Compression:         "zstd",
+				CompressionLevel:    3,
+				SessionName:         "APPSESSID",
+				CookieDomain:        "example.com",
+				CookiePath:          "/app",
+				CookieSecure:        &enabled,
+				CookieHttpOnly:      &enabled,
+				CookieSameSite:      "Strict",
+				UseStrictMode:       &enabled,
+				SidLength:           48,
+				SidBitsPerCharacter: 6,
+			}
+		})
+
+		it("renders every setting into php-redis.ini", func() {
+			path, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, filepath.Join(buildpackDir, "config", "php-redis.ini"))
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal(`extension=redis.so
+extension=igbinary.so
+
+[session]
+session.save_handler = redis
+session.save_path = "tls://redis.example.com:6380?auth=some+%22pass%22word%24&database=2&prefix=app%3A&stream%5Bcafile%5D=%2Fetc%2Fssl%2Fredis-ca.pem&stream%5Bverify_peer%5D=0"
+session.name = APPSESSID
+session.cookie_domain = "example.com"
+session.cookie_path = "/app"
+session.cookie_secure = On
+session.cookie_httponly = On
+session.cookie_samesite = "Strict"
+session.use_strict_mode = On
+session.sid_length = 48
+session.sid_bits_per_character = 6
+session.serialize_handler = igbinary
+session.gc_maxlifetime = 3600
+session.lazy_write = Off
+redis.session.locking_enabled = 1
+redis.session.lock_expire = 30
+redis.session.lock_wait_time = 20000
+redis.session.lock_retries = 50
+redis.session.early_refresh = 1
+redis.session.compression = zstd
+redis.session.compression_level = 3
+`))
+		})
+
+		it("renders the php-fpm pool configuration", func() {
+			path, err := redisConfigWriter.WriteFpmPool(redisConfig, "app", layerDir, buildpackDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal(`; Locks the session handler of the app pool. Generated by the
+; php-redis-session-handler buildpack; do not edit.
+[app]
+php_admin_value[session.save_handler] = redis
+php_admin_value[session.save_path] = "tls://redis.example.com:6380?auth=some+%22pass%22word%24&database=2&prefix=app%3A&stream%5Bcafile%5D=%2Fetc%2Fssl%2Fredis-ca.pem&stream%5Bverify_peer%5D=0"
+`))
+		})
+
+		context("when the session client is predis", func() {
+			it.Before(func() {
+				redisConfig.Client = "predis"
+				redisConfig.LockingEnabled = false
+				redisConfig.EarlyRefresh = false
+				redisConfig.Compression = ""
+				redisConfig.CompressionLevel = 0
+			})
+
+			it("renders php-redis.ini without a save handler", func() {
+				path, err := redisConfigWriter.Write(redisConfig, nil, layerDir, filepath.Join(buildpackDir, "config", "php-redis.ini"))
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := os.ReadFile(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal(fmt.Sprintf(`auto_prepend_file = "%s"
+
+[session]
+session.name = APPSESSID
+session.cookie_domain = "example.com"
+session.cookie_path = "/app"
+session.cookie_secure = On
+session.cookie_httponly = On
+session.cookie_samesite = "Strict"
+session.use_strict_mode = On
+session.sid_length = 48
+session.sid_bits_per_character = 6
+session.serialize_handler = igbinary
+session.gc_maxlifetime = 3600
+session.lazy_write = Off
+`, filepath.Join(layerDir, "predis-session-handler.php"))))
+			})
+
+			it("renders the Predis session handler", func() {
+				path, err := redisConfigWriter.WritePredisHandler(redisConfig, "/layers/predis/vendor", layerDir, buildpackDir)
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := os.ReadFile(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(contents)).To(Equal(`<?php
+// Registers a Redis session handler backed by Predis. Generated by the
+// php-redis-session-handler buildpack; do not edit.
+
+if (!class_exists('PaketoPredisSessionHandler', false)) {
+    require_once '/layers/predis/vendor/autoload.php';
+
+    final class PaketoPredisSessionHandler implements SessionHandlerInterface
+    {
+        private $client;
+
+        public function __construct(Predis\ClientInterface $client)
+        {
+            $this->client = $client;
+        }
+
+        #[\ReturnTypeWillChange]
+        public function open($path, $name)
+        {
+            return true;
+        }
+
+        #[\ReturnTypeWillChange]
+        public function close()
+        {
+            return true;
+        }
+
+        #[\ReturnTypeWillChange]
+        public function read($id)
+        {
+            $data = $this->client->get($id);
+
+            return $data === null ? '' : $data;
+        }
+
+        #[\ReturnTypeWillChange]
+        public function write($id, $data)
+        {
+            $this->client->setex($id, max(1, (int) ini_get('session.gc_maxlifetime')), $data);
+
+            return true;
+        }
+
+        #[\ReturnTypeWillChange]
+        public function destroy($id)
+        {
+            $this->client->del([$id]);
+
+            return true;
+        }
+
+        #[\ReturnTypeWillChange]
+        public function gc($max_lifetime)
+        {
+            // Sessions expire through their TTL.
+            return 0;
+        }
+    }
+
+    if (session_status() === PHP_SESSION_NONE) {
+        session_set_save_handler(new PaketoPredisSessionHandler(new Predis\Client(
+            ['scheme' => 'tls', 'host' => 'redis.example.com', 'port' => 6380, 'ssl' => ['verify_peer' => false, 'cafile' => '/etc/ssl/redis-ca.pem'], 'password' => 'some "pass"word$', 'database' => 2],
+            ['prefix' => 'app:']
+        )), true);
+    }
+}
+`))
+			})
+		})
+	})
+
 	context("failure cases", func() {
 		context("when template is not parseable", func() {
 			it.Before(func() {
@@ -86,18 +714,29 @@ func testRedisConfigWriter(t *testing.T, context spec.G, it spec.S) {
 			})
 
 			it("returns an error", func() {
-				_, err := redisConfigWriter.Write(redisConfig, layerDir, cnbDir)
+				_, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, templatePath)
 				Expect(err).To(MatchError(ContainSubstring("failed to parse PHP redis config template")))
 			})
 		})
 
+		context("when the client is unknown", func() {
+			it.Before(func() {
+				redisConfig.Client = "some-client"
+			})
+
+			it("returns an error", func() {
+				_, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, templatePath)
+				Expect(err).To(MatchError(ContainSubstring(`unknown session client "some-client"`)))
+			})
+		})
+
 		context("when redis config file can't be opened for writing", func() {
 			it.Before(func() {
 				Expect(os.WriteFile(filepath.Join(layerDir, "php-redis.ini"), nil, 0400)).To(Succeed())
 			})
 
 			it("returns an error", func() {
-				_, err := redisConfigWriter.Write(redisConfig, layerDir, cnbDir)
+				_, err := redisConfigWriter.Write(redisConfig, extensions, layerDir, templatePath)
 				Expect(err).To(MatchError(ContainSubstring("permission denied")))
 			})
 		})
@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/pexec"
 	"github.com/paketo-buildpacks/packit/v2/scribe"
 	"github.com/paketo-buildpacks/packit/v2/servicebindings"
 	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
@@ -12,15 +13,21 @@ import (
 func main() {
 	logEmitter := scribe.NewEmitter(os.Stdout).WithLevel(os.Getenv("BP_LOG_LEVEL"))
 	serviceResolver := servicebindings.NewResolver()
+	environment := phpredishandler.LoadEnvironment(os.Environ())
 
 	packit.Run(
 		phpredishandler.Detect(
 			serviceResolver,
+			environment,
 		),
 		phpredishandler.Build(
 			phpredishandler.NewRedisConfigParser(),
+			phpredishandler.NewMemcachedConfigParser(),
 			serviceResolver,
 			phpredishandler.NewRedisConfigWriter(logEmitter),
+			phpredishandler.NewMemcachedConfigWriter(logEmitter),
+			phpredishandler.NewPhpExtensionInspector(pexec.NewExecutable("php")),
+			environment,
 			logEmitter,
 		),
 	)
@@ -0,0 +1,84 @@
+package phpredishandler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	SessionClientPhpRedis = "phpredis"
+	SessionClientRelay    = "relay"
+	SessionClientPredis   = "predis"
+)
+
+// SessionClient is a PHP extension that provides a Redis session handler.
+type SessionClient struct {
+	Name string
+
+	// SaveHandler is the session.save_handler the extension registers. It is
+	// empty for pure-PHP clients, which register a SessionHandlerInterface
+	// from an auto_prepend_file instead.
+	SaveHandler string
+
+	// Extensions are the PHP extensions the client needs, including the
+	// serializers it is built against. Serializers that are only used as the
+	// session.serialize_handler are added by RedisConfig.Extensions.
+	Extensions []string
+}
+
+var sessionClients = []SessionClient{
+	{
+		Name:        SessionClientPhpRedis,
+		SaveHandler: "redis",
+		Extensions:  []string{"redis"},
+	},
+	{
+		Name:        SessionClientRelay,
+		SaveHandler: "relay",
+		Extensions:  []string{"relay", "igbinary", "msgpack"},
+	},
+	{
+		Name: SessionClientPredis,
+	},
+}
+
+// PurePHP reports whether the client is implemented in PHP rather than as an
+// extension.
+func (c SessionClient) PurePHP() bool {
+	return c.SaveHandler == ""
+}
+
+// LookupSessionClient returns the named session client. An empty name selects
+// phpredis.
+func LookupSessionClient(name string) (SessionClient, error) {
+	if name == "" {
+		name = SessionClientPhpRedis
+	}
+
+	for _, client := range sessionClients {
+		if client.Name == name {
+			return client, nil
+		}
+	}
+
+	return SessionClient{}, fmt.Errorf("unknown session client %q: must be one of %q, %q or %q", name, SessionClientPhpRedis, SessionClientRelay, SessionClientPredis)
+}
+
+// VendoredPredis returns the Predis package that Composer installed into the
+// PredisVendorDir of the application. It returns an error when the
+// application does not require predis/predis.
+func VendoredPredis(workingDir string) (string, error) {
+	path := filepath.Join(workingDir, PredisVendorDir)
+	_, err := os.Stat(filepath.Join(path, "autoload.php"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("the application does not require predis/predis with Composer: %s is missing", filepath.Join(PredisVendorDir, "autoload.php"))
+		}
+
+		return "", err
+	}
+
+	return path, nil
+}
@@ -0,0 +1,74 @@
+package phpredishandler_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSessionClient(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	it("defaults to phpredis", func() {
+		client, err := phpredishandler.LookupSessionClient("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client).To(Equal(phpredishandler.SessionClient{
+			Name:        "phpredis",
+			SaveHandler: "redis",
+			Extensions:  []string{"redis"},
+		}))
+	})
+
+	it("returns relay", func() {
+		client, err := phpredishandler.LookupSessionClient("relay")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client).To(Equal(phpredishandler.SessionClient{
+			Name:        "relay",
+			SaveHandler: "relay",
+			Extensions:  []string{"relay", "igbinary", "msgpack"},
+		}))
+	})
+
+	it("returns predis", func() {
+		client, err := phpredishandler.LookupSessionClient("predis")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client).To(Equal(phpredishandler.SessionClient{Name: "predis"}))
+		Expect(client.PurePHP()).To(BeTrue())
+	})
+
+	context("when the client is unknown", func() {
+		it("returns an error", func() {
+			_, err := phpredishandler.LookupSessionClient("some-client")
+			Expect(err).To(MatchError(`unknown session client "some-client": must be one of "phpredis", "relay" or "predis"`))
+		})
+	})
+
+	context("VendoredPredis", func() {
+		var workingDir string
+
+		it.Before(func() {
+			workingDir = t.TempDir()
+		})
+
+		it("returns the Predis package Composer installed", func() {
+			Expect(os.MkdirAll(filepath.Join(workingDir, "vendor", "predis", "predis"), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(workingDir, "vendor", "predis", "predis", "autoload.php"), nil, os.ModePerm)).To(Succeed())
+
+			path, err := phpredishandler.VendoredPredis(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(workingDir, "vendor", "predis", "predis")))
+		})
+
+		context("when the application does not require predis/predis", func() {
+			it("returns an error", func() {
+				_, err := phpredishandler.VendoredPredis(workingDir)
+				Expect(err).To(MatchError("the application does not require predis/predis with Composer: vendor/predis/predis/autoload.php is missing"))
+			})
+		})
+	})
+}
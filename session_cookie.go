@@ -0,0 +1,84 @@
+package phpredishandler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithHardening returns a copy of the config with the hardened preset applied
+// when Hardened is set. The preset only fills the cookie settings that are
+// not configured, so explicit settings take precedence.
+func (c RedisConfig) WithHardening() RedisConfig {
+	if !c.Hardened {
+		return c
+	}
+
+	enabled := true
+	if c.CookieSecure == nil {
+		c.CookieSecure = &enabled
+	}
+
+	if c.CookieHttpOnly == nil {
+		c.CookieHttpOnly = &enabled
+	}
+
+	if c.CookieSameSite == "" {
+		c.CookieSameSite = "Lax"
+	}
+
+	if c.UseStrictMode == nil {
+		c.UseStrictMode = &enabled
+	}
+
+	return c
+}
+
+// SessionCookieName returns the session.name, falling back to the PHP
+// default when none has been configured.
+func (c RedisConfig) SessionCookieName() string {
+	if c.SessionName == "" {
+		return DefaultSessionName
+	}
+
+	return c.SessionName
+}
+
+// CookieSummary describes the effective session cookie settings for the build
+// log. Settings that keep their PHP default are reported as default.
+func (c RedisConfig) CookieSummary() string {
+	c = c.WithHardening()
+
+	summary := []string{fmt.Sprintf("name=%s", c.SessionCookieName())}
+	for _, setting := range []struct {
+		name  string
+		value string
+	}{
+		{"domain", c.CookieDomain},
+		{"path", c.CookiePath},
+		{"secure", iniBool(c.CookieSecure)},
+		{"httponly", iniBool(c.CookieHttpOnly)},
+		{"samesite", c.CookieSameSite},
+		{"strict_mode", iniBool(c.UseStrictMode)},
+	} {
+		value := setting.value
+		if value == "" {
+			value = "default"
+		}
+		summary = append(summary, fmt.Sprintf("%s=%s", setting.name, value))
+	}
+
+	return strings.Join(summary, " ")
+}
+
+// iniBool renders an optional boolean as an ini value, or an empty string
+// when it is nil.
+func iniBool(value *bool) string {
+	switch {
+	case value == nil:
+		return ""
+	case *value:
+		return "On"
+	default:
+		return "Off"
+	}
+}
@@ -0,0 +1,64 @@
+package phpredishandler_test
+
+import (
+	"testing"
+
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSessionCookie(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		config phpredishandler.RedisConfig
+	)
+
+	it.Before(func() {
+		config = phpredishandler.RedisConfig{}
+	})
+
+	context("WithHardening", func() {
+		it("leaves the config unchanged unless hardened", func() {
+			Expect(config.WithHardening()).To(Equal(config))
+		})
+
+		it("fills the cookie settings that are not configured", func() {
+			httpOnly := false
+			config.Hardened = true
+			config.CookieHttpOnly = &httpOnly
+			config.CookieSameSite = "Strict"
+
+			hardened := config.WithHardening()
+			Expect(*hardened.CookieSecure).To(BeTrue())
+			Expect(*hardened.CookieHttpOnly).To(BeFalse())
+			Expect(hardened.CookieSameSite).To(Equal("Strict"))
+			Expect(*hardened.UseStrictMode).To(BeTrue())
+
+			Expect(config.CookieSecure).To(BeNil())
+		})
+	})
+
+	context("SessionCookieName", func() {
+		it("falls back to the PHP default", func() {
+			Expect(config.SessionCookieName()).To(Equal("PHPSESSID"))
+
+			config.SessionName = "APPSESSION"
+			Expect(config.SessionCookieName()).To(Equal("APPSESSION"))
+		})
+	})
+
+	context("CookieSummary", func() {
+		it("reports the settings that keep their PHP default", func() {
+			Expect(config.CookieSummary()).To(Equal("name=PHPSESSID domain=default path=default secure=default httponly=default samesite=default strict_mode=default"))
+		})
+
+		it("reports the effective settings", func() {
+			config.Hardened = true
+			config.CookieDomain = "example.com"
+			Expect(config.CookieSummary()).To(Equal("name=PHPSESSID domain=example.com path=default secure=On httponly=On samesite=Lax strict_mode=On"))
+		})
+	})
+}
@@ -0,0 +1,142 @@
+package phpredishandler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+const (
+	CopyMethodDump   = "dump"
+	CopyMethodGetSet = "get-set"
+)
+
+type SessionCopyOptions struct {
+	// Method is either CopyMethodDump, which copies keys with DUMP/RESTORE, or
+	// CopyMethodGetSet, which copies string values with GET/SET and works
+	// across incompatible Redis versions.
+	Method string
+
+	// DryRun reports the keys that would be copied without writing to the
+	// destination.
+	DryRun bool
+
+	// ProgressInterval is the number of keys between progress reports.
+	ProgressInterval int
+}
+
+type SessionCopyReport struct {
+	Copied  int
+	Expired int
+}
+
+type SessionCopier struct {
+	logger scribe.Emitter
+}
+
+func NewSessionCopier(logger scribe.Emitter) SessionCopier {
+	return SessionCopier{
+		logger: logger,
+	}
+}
+
+// Copy copies every session key under sourcePrefix from the source to the
+// destination, keeping each key's remaining time to live. Keys are renamed to
+// destinationPrefix when the two prefixes differ.
+func (c SessionCopier) Copy(source, destination *RedisClient, sourcePrefix, destinationPrefix string, options SessionCopyOptions) (SessionCopyReport, error) {
+	if options.Method == "" {
+		options.Method = CopyMethodDump
+	}
+
+	if options.Method != CopyMethodDump && options.Method != CopyMethodGetSet {
+		return SessionCopyReport{}, fmt.Errorf("unknown copy method %q: must be one of %q or %q", options.Method, CopyMethodDump, CopyMethodGetSet)
+	}
+
+	if options.ProgressInterval <= 0 {
+		options.ProgressInterval = 1000
+	}
+
+	var report SessionCopyReport
+	err := source.ScanPrefix(sourcePrefix, func(keys []string) error {
+		for _, key := range keys {
+			ttl, exists, err := source.PTTL(key)
+			if err != nil {
+				return fmt.Errorf("failed to read the TTL of %s: %w", key, err)
+			}
+
+			if !exists {
+				report.Expired++
+				continue
+			}
+
+			target := destinationPrefix + strings.TrimPrefix(key, sourcePrefix)
+			if options.DryRun {
+				c.logger.Debug.Action("Would copy %s to %s", key, target)
+				report.Copied++
+				continue
+			}
+
+			copied, err := c.copyKey(source, destination, key, target, ttl, options.Method)
+			if err != nil {
+				return err
+			}
+
+			if !copied {
+				report.Expired++
+				continue
+			}
+
+			report.Copied++
+			if report.Copied%options.ProgressInterval == 0 {
+				c.logger.Action("Copied %d session(s)", report.Copied)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("failed to copy sessions: %w", err)
+	}
+
+	return report, nil
+}
+
+// copyKey copies a single key and reports whether it still existed on the
+// source.
+func (c SessionCopier) copyKey(source, destination *RedisClient, key, target string, ttl time.Duration, method string) (bool, error) {
+	if method == CopyMethodGetSet {
+		value, err := source.Get(key)
+		if err != nil {
+			return false, fmt.Errorf("failed to read %s: %w", key, err)
+		}
+
+		if value == nil {
+			return false, nil
+		}
+
+		err = destination.Set(target, value, ttl)
+		if err != nil {
+			return false, fmt.Errorf("failed to write %s: %w", target, err)
+		}
+
+		return true, nil
+	}
+
+	payload, err := source.Dump(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to dump %s: %w", key, err)
+	}
+
+	if payload == nil {
+		return false, nil
+	}
+
+	err = destination.Restore(target, payload, ttl)
+	if err != nil {
+		return false, fmt.Errorf("failed to restore %s: %w", target, err)
+	}
+
+	return true, nil
+}
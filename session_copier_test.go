@@ -0,0 +1,156 @@
+package phpredishandler_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/paketo-buildpacks/php-redis-session-handler/fakes"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSessionCopier(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		sourceServer      *fakes.RedisServer
+		destinationServer *fakes.RedisServer
+		source            *phpredishandler.RedisClient
+		destination       *phpredishandler.RedisClient
+		buffer            *bytes.Buffer
+
+		copier phpredishandler.SessionCopier
+	)
+
+	it.Before(func() {
+		var err error
+		sourceServer, err = fakes.NewRedisServer()
+		Expect(err).NotTo(HaveOccurred())
+
+		destinationServer, err = fakes.NewRedisServer()
+		Expect(err).NotTo(HaveOccurred())
+
+		source, err = phpredishandler.DialRedis(phpredishandler.RedisConfig{Hostname: sourceServer.Hostname(), Port: sourceServer.Port()}, time.Second)
+		Expect(err).NotTo(HaveOccurred())
+
+		destination, err = phpredishandler.DialRedis(phpredishandler.RedisConfig{Hostname: destinationServer.Hostname(), Port: destinationServer.Port()}, time.Second)
+		Expect(err).NotTo(HaveOccurred())
+
+		sourceServer.Seed("PHPREDIS_SESSION:some-session", []byte("some-data"), time.Hour)
+		sourceServer.Seed("PHPREDIS_SESSION:other-session", []byte("other-data"), 0)
+		sourceServer.Seed("cache:some-key", []byte("some-cache"), time.Hour)
+
+		buffer = bytes.NewBuffer(nil)
+		copier = phpredishandler.NewSessionCopier(scribe.NewEmitter(buffer))
+	})
+
+	it.After(func() {
+		_ = source.Close()
+		_ = destination.Close()
+		Expect(sourceServer.Close()).To(Succeed())
+		Expect(destinationServer.Close()).To(Succeed())
+	})
+
+	it("copies the session keys with their remaining TTL using DUMP/RESTORE", func() {
+		report, err := copier.Copy(source, destination, "PHPREDIS_SESSION:", "PHPREDIS_SESSION:", phpredishandler.SessionCopyOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(report).To(Equal(phpredishandler.SessionCopyReport{Copied: 2}))
+		Expect(destinationServer.Keys()).To(Equal([]string{"PHPREDIS_SESSION:other-session", "PHPREDIS_SESSION:some-session"}))
+
+		value, ttl, _ := destinationServer.Lookup("PHPREDIS_SESSION:some-session")
+		Expect(string(value)).To(Equal("some-data"))
+		Expect(ttl).To(BeNumerically("~", time.Hour, time.Second))
+
+		value, ttl, _ = destinationServer.Lookup("PHPREDIS_SESSION:other-session")
+		Expect(string(value)).To(Equal("other-data"))
+		Expect(ttl).To(BeZero())
+
+		Expect(destinationServer.Commands()).To(ContainElement(HaveExactElements("RESTORE", "PHPREDIS_SESSION:other-session", "0", "DUMP:other-data", "REPLACE")))
+	})
+
+	context("when the destination uses a different prefix", func() {
+		it("renames the keys", func() {
+			_, err := copier.Copy(source, destination, "PHPREDIS_SESSION:", "new:", phpredishandler.SessionCopyOptions{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(destinationServer.Keys()).To(Equal([]string{"new:other-session", "new:some-session"}))
+		})
+	})
+
+	context("when the get-set method is used", func() {
+		it("copies the session keys using GET/SET", func() {
+			report, err := copier.Copy(source, destination, "PHPREDIS_SESSION:", "PHPREDIS_SESSION:", phpredishandler.SessionCopyOptions{
+				Method: phpredishandler.CopyMethodGetSet,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(report.Copied).To(Equal(2))
+
+			value, ttl, _ := destinationServer.Lookup("PHPREDIS_SESSION:some-session")
+			Expect(string(value)).To(Equal("some-data"))
+			Expect(ttl).To(BeNumerically("~", time.Hour, time.Second))
+
+			Expect(destinationServer.Commands()).NotTo(ContainElement(ContainElement("RESTORE")))
+		})
+	})
+
+	context("when it is a dry run", func() {
+		it("does not write to the destination", func() {
+			report, err := copier.Copy(source, destination, "PHPREDIS_SESSION:", "PHPREDIS_SESSION:", phpredishandler.SessionCopyOptions{
+				DryRun: true,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(report.Copied).To(Equal(2))
+			Expect(destinationServer.Keys()).To(BeEmpty())
+		})
+	})
+
+	context("when there are many sessions", func() {
+		it.Before(func() {
+			for i := 0; i < 248; i++ {
+				sourceServer.Seed(fmt.Sprintf("PHPREDIS_SESSION:session-%03d", i), []byte("some-data"), time.Hour)
+			}
+		})
+
+		it("copies every page and reports progress", func() {
+			report, err := copier.Copy(source, destination, "PHPREDIS_SESSION:", "PHPREDIS_SESSION:", phpredishandler.SessionCopyOptions{
+				ProgressInterval: 100,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(report.Copied).To(Equal(250))
+			Expect(destinationServer.Keys()).To(HaveLen(250))
+			Expect(buffer.String()).To(ContainSubstring("Copied 100 session(s)"))
+			Expect(buffer.String()).To(ContainSubstring("Copied 200 session(s)"))
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the copy method is unknown", func() {
+			it("returns an error", func() {
+				_, err := copier.Copy(source, destination, "PHPREDIS_SESSION:", "PHPREDIS_SESSION:", phpredishandler.SessionCopyOptions{
+					Method: "some-method",
+				})
+				Expect(err).To(MatchError(ContainSubstring(`unknown copy method "some-method"`)))
+			})
+		})
+
+		context("when the destination rejects the restore", func() {
+			it.Before(func() {
+				Expect(destinationServer.Close()).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := copier.Copy(source, destination, "PHPREDIS_SESSION:", "PHPREDIS_SESSION:", phpredishandler.SessionCopyOptions{})
+				Expect(err).To(MatchError(ContainSubstring("failed to copy sessions: failed to restore")))
+			})
+		})
+	})
+}
@@ -0,0 +1,273 @@
+package phpredishandler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/paketo-buildpacks/packit/v2/chronos"
+)
+
+// SessionMetrics is a single sample of the session storage. Up reports
+// whether Redis answered a PING. KeysTruncated reports that the session keys
+// were not all counted, so SessionKeys is a lower bound. SessionBytes is
+// estimated from the SizedKeys session keys whose memory usage was read, and
+// is only known when SizedKeys is not zero.
+type SessionMetrics struct {
+	Up              bool
+	Error           string
+	SessionKeys     int64
+	KeysTruncated   bool
+	SizedKeys       int64
+	SessionBytes    int64
+	UsedMemoryBytes int64
+	PingLatency     time.Duration
+	SampledAt       time.Time
+	SampleDuration  time.Duration
+}
+
+// SessionMetricsExporter periodically samples the session keys stored in
+// Redis and serves the latest sample in the Prometheus text format. The
+// timeout bounds connecting and the PING, and the scan timeout bounds
+// reading the memory info and counting the session keys.
+type SessionMetricsExporter struct {
+	config      RedisConfig
+	timeout     time.Duration
+	scanTimeout time.Duration
+	clock       chronos.Clock
+	sizeSamples int
+
+	mutex  sync.RWMutex
+	latest SessionMetrics
+}
+
+func NewSessionMetricsExporter(config RedisConfig, timeout time.Duration, clock chronos.Clock) *SessionMetricsExporter {
+	return &SessionMetricsExporter{
+		config:      config,
+		timeout:     timeout,
+		scanTimeout: timeout,
+		clock:       clock,
+	}
+}
+
+// WithScanTimeout bounds reading the memory info and counting the session
+// keys of each sample by d instead of the timeout.
+func (e *SessionMetricsExporter) WithScanTimeout(d time.Duration) *SessionMetricsExporter {
+	e.scanTimeout = d
+	return e
+}
+
+// WithSizeSamples reads the memory usage of up to n session keys per sample,
+// one MEMORY USAGE round trip each, to estimate the memory used by all of
+// them. Sizing is off by default.
+func (e *SessionMetricsExporter) WithSizeSamples(n int) *SessionMetricsExporter {
+	e.sizeSamples = n
+	return e
+}
+
+// Sample connects to Redis, collects a new sample and records it as the
+// latest one. Failures are recorded on the sample rather than returned.
+// Failing to connect, authenticate or PING surfaces as php_redis_session_up
+// 0; failing later, such as a SCAN that outlasts the scan timeout on a large
+// keyspace, only surfaces as php_redis_session_keys_truncated 1.
+func (e *SessionMetricsExporter) Sample() SessionMetrics {
+	metrics := SessionMetrics{SampledAt: e.clock.Now()}
+
+	var collectErr error
+	duration, err := e.clock.Measure(func() error {
+		client, err := e.ping(&metrics)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = client.Close()
+		}()
+
+		collectErr = e.collect(client, &metrics)
+		return nil
+	})
+	metrics.SampleDuration = duration
+	metrics.Up = err == nil
+	metrics.KeysTruncated = collectErr != nil
+
+	switch {
+	case err != nil:
+		metrics.Error = err.Error()
+	case collectErr != nil:
+		metrics.Error = collectErr.Error()
+	}
+
+	e.mutex.Lock()
+	e.latest = metrics
+	e.mutex.Unlock()
+
+	return metrics
+}
+
+// ping connects to Redis and measures the latency of a PING, all within the
+// timeout.
+func (e *SessionMetricsExporter) ping(metrics *SessionMetrics) (*RedisClient, error) {
+	client, err := DialRedis(e.config, e.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.SetDeadline(time.Now().Add(e.timeout))
+	if err != nil {
+		// not tested
+		_ = client.Close()
+		return nil, err
+	}
+
+	metrics.PingLatency, err = e.clock.Measure(func() error {
+		_, err := client.Do("PING")
+		return err
+	})
+	if err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to ping redis: %w", err)
+	}
+
+	return client, nil
+}
+
+// collect reads the memory info and counts the session keys within the scan
+// timeout.
+func (e *SessionMetricsExporter) collect(client *RedisClient, metrics *SessionMetrics) error {
+	err := client.SetDeadline(time.Now().Add(e.scanTimeout))
+	if err != nil {
+		// not tested
+		return err
+	}
+
+	err = e.scan(client, metrics)
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("counting the session keys did not finish within %s: %w", e.scanTimeout, err)
+	}
+
+	return err
+}
+
+func (e *SessionMetricsExporter) scan(client *RedisClient, metrics *SessionMetrics) error {
+	info, err := client.Info("memory")
+	if err != nil {
+		return fmt.Errorf("failed to read redis memory info: %w", err)
+	}
+
+	metrics.UsedMemoryBytes, err = strconv.ParseInt(info["used_memory"], 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse used_memory: %w", err)
+	}
+
+	var sizedBytes int64
+	err = client.ScanPrefix(e.config.SessionKeyPrefix(), func(keys []string) error {
+		for _, key := range keys {
+			metrics.SessionKeys++
+
+			if metrics.SizedKeys >= int64(e.sizeSamples) {
+				continue
+			}
+
+			usage, err := client.MemoryUsage(key)
+			if err != nil {
+				return fmt.Errorf("failed to read the memory usage of %s: %w", key, err)
+			}
+
+			metrics.SizedKeys++
+			sizedBytes += usage
+		}
+
+		return nil
+	})
+
+	// The sized keys are the first ones SCAN returns, which are spread
+	// over the hash slots rather than ordered by age or size.
+	if metrics.SizedKeys > 0 {
+		metrics.SessionBytes = sizedBytes * metrics.SessionKeys / metrics.SizedKeys
+	}
+
+	return err
+}
+
+// Run samples at the given interval until stop is closed.
+func (e *SessionMetricsExporter) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		e.Sample()
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// ServeHTTP writes the latest sample in the Prometheus text exposition format.
+func (e *SessionMetricsExporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	e.mutex.RLock()
+	metrics := e.latest
+	e.mutex.RUnlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	WriteSessionMetrics(w, metrics)
+}
+
+// WriteSessionMetrics writes a sample in the Prometheus text exposition format.
+func WriteSessionMetrics(w io.Writer, metrics SessionMetrics) {
+	up := 0
+	if metrics.Up {
+		up = 1
+	}
+
+	truncated := 0
+	if metrics.KeysTruncated {
+		truncated = 1
+	}
+
+	type gauge struct {
+		name  string
+		help  string
+		value string
+	}
+
+	gauges := []gauge{
+		{"php_redis_session_up", "Whether the last sample reached Redis and got a reply to PING.", strconv.Itoa(up)},
+		{"php_redis_session_keys", "Number of session keys under the configured prefix.", strconv.FormatInt(metrics.SessionKeys, 10)},
+		{"php_redis_session_keys_truncated", "Whether the last sample stopped before counting all session keys.", strconv.Itoa(truncated)},
+	}
+
+	// Without sized keys the memory used by the sessions is unknown.
+	if metrics.SizedKeys > 0 {
+		gauges = append(gauges,
+			gauge{"php_redis_session_keys_bytes", "Estimated memory used by the session keys under the configured prefix.", strconv.FormatInt(metrics.SessionBytes, 10)},
+			gauge{"php_redis_session_sized_keys", "Number of session keys whose memory usage the estimate is based on.", strconv.FormatInt(metrics.SizedKeys, 10)},
+		)
+	}
+
+	gauges = append(gauges, []gauge{
+		{"php_redis_session_used_memory_bytes", "Memory used by the Redis server.", strconv.FormatInt(metrics.UsedMemoryBytes, 10)},
+		{"php_redis_session_ping_latency_seconds", "Round trip time of a PING to the Redis server.", formatSeconds(metrics.PingLatency)},
+		{"php_redis_session_sample_duration_seconds", "Time taken to collect the last sample.", formatSeconds(metrics.SampleDuration)},
+		{"php_redis_session_last_sample_timestamp_seconds", "Unix time of the last sample.", strconv.FormatInt(metrics.SampledAt.Unix(), 10)},
+	}...)
+
+	for _, gauge := range gauges {
+		fmt.Fprintf(w, "# HELP %s %s\n", gauge.name, gauge.help)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", gauge.name)
+		fmt.Fprintf(w, "%s %s\n", gauge.name, gauge.value)
+	}
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
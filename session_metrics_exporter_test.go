@@ -0,0 +1,173 @@
+package phpredishandler_test
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/paketo-buildpacks/packit/v2/chronos"
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/paketo-buildpacks/php-redis-session-handler/fakes"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSessionMetricsExporter(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		server   *fakes.RedisServer
+		exporter *phpredishandler.SessionMetricsExporter
+	)
+
+	it.Before(func() {
+		var err error
+		server, err = fakes.NewRedisServer()
+		Expect(err).NotTo(HaveOccurred())
+
+		server.Password = "some-password"
+		server.Seed("some-prefix:some-session", []byte("some-data"), time.Hour)
+		server.Seed("some-prefix:other-session", []byte("other-data"), time.Hour)
+		server.Seed("cache:some-key", []byte("some-value"), 0)
+
+		now := time.Unix(1700000000, 0)
+		clock := chronos.NewClock(func() time.Time {
+			now = now.Add(time.Millisecond)
+			return now
+		})
+
+		exporter = phpredishandler.NewSessionMetricsExporter(phpredishandler.RedisConfig{
+			Hostname: server.Hostname(),
+			Port:     server.Port(),
+			Password: "some-password",
+			Prefix:   "some-prefix:",
+		}, time.Second, clock)
+	})
+
+	it.After(func() {
+		Expect(server.Close()).To(Succeed())
+	})
+
+	it("samples the session keys and redis memory without sizing the keys", func() {
+		metrics := exporter.Sample()
+		Expect(metrics.Error).To(BeEmpty())
+		Expect(metrics.Up).To(BeTrue())
+		Expect(metrics.SessionKeys).To(Equal(int64(2)))
+		Expect(metrics.SizedKeys).To(BeZero())
+		Expect(metrics.SessionBytes).To(BeZero())
+		Expect(metrics.UsedMemoryBytes).To(BeNumerically(">", 0))
+		Expect(metrics.PingLatency).To(Equal(time.Millisecond))
+		Expect(metrics.SampledAt).To(Equal(time.Unix(1700000000, 0).Add(time.Millisecond)))
+
+		for _, command := range server.Commands() {
+			Expect(command[0]).NotTo(Equal("MEMORY"))
+		}
+
+		recorder := httptest.NewRecorder()
+		exporter.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+		Expect(recorder.Body.String()).NotTo(ContainSubstring("php_redis_session_keys_bytes"))
+	})
+
+	context("when size samples are set", func() {
+		it.Before(func() {
+			exporter.WithSizeSamples(1)
+		})
+
+		it("sizes that many keys and estimates the memory of all of them", func() {
+			metrics := exporter.Sample()
+			Expect(metrics.Error).To(BeEmpty())
+			Expect(metrics.SessionKeys).To(Equal(int64(2)))
+			Expect(metrics.SizedKeys).To(Equal(int64(1)))
+
+			var sized []string
+			for _, command := range server.Commands() {
+				if command[0] == "MEMORY" {
+					sized = append(sized, command[2])
+				}
+			}
+			Expect(sized).To(HaveLen(1))
+
+			value, _, _ := server.Lookup(sized[0])
+			Expect(metrics.SessionBytes).To(Equal(int64(2 * (len(sized[0]) + len(value)))))
+
+			recorder := httptest.NewRecorder()
+			exporter.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+			Expect(recorder.Body.String()).To(ContainSubstring(fmt.Sprintf("\nphp_redis_session_keys_bytes %d\n", metrics.SessionBytes)))
+			Expect(recorder.Body.String()).To(ContainSubstring("\nphp_redis_session_sized_keys 1\n"))
+		})
+	})
+
+	context("when counting the session keys takes longer than the scan timeout", func() {
+		it.Before(func() {
+			server.Latency = 100 * time.Millisecond
+			exporter = phpredishandler.NewSessionMetricsExporter(phpredishandler.RedisConfig{
+				Hostname: server.Hostname(),
+				Port:     server.Port(),
+				Password: "some-password",
+				Prefix:   "some-prefix:",
+			}, time.Second, chronos.DefaultClock).WithScanTimeout(150 * time.Millisecond)
+		})
+
+		it("reports the sample as up with truncated session keys", func() {
+			metrics := exporter.Sample()
+			Expect(metrics.Up).To(BeTrue())
+			Expect(metrics.KeysTruncated).To(BeTrue())
+			Expect(metrics.Error).To(ContainSubstring("counting the session keys did not finish within 150ms"))
+
+			recorder := httptest.NewRecorder()
+			exporter.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+			Expect(recorder.Body.String()).To(ContainSubstring("\nphp_redis_session_up 1\n"))
+			Expect(recorder.Body.String()).To(ContainSubstring("\nphp_redis_session_keys_truncated 1\n"))
+		})
+	})
+
+	context("when the PING takes longer than the timeout", func() {
+		it.Before(func() {
+			server.Latency = 200 * time.Millisecond
+			exporter = phpredishandler.NewSessionMetricsExporter(phpredishandler.RedisConfig{
+				Hostname: server.Hostname(),
+				Port:     server.Port(),
+				Prefix:   "some-prefix:",
+			}, 100*time.Millisecond, chronos.DefaultClock)
+		})
+
+		it("reports the sample as down", func() {
+			metrics := exporter.Sample()
+			Expect(metrics.Up).To(BeFalse())
+			Expect(metrics.KeysTruncated).To(BeFalse())
+			Expect(metrics.Error).To(ContainSubstring("failed to ping redis"))
+		})
+	})
+
+	it("serves the latest sample in the prometheus text format", func() {
+		exporter.Sample()
+
+		recorder := httptest.NewRecorder()
+		exporter.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+		Expect(recorder.Header().Get("Content-Type")).To(HavePrefix("text/plain; version=0.0.4"))
+		Expect(recorder.Body.String()).To(ContainSubstring("# HELP php_redis_session_up Whether the last sample reached Redis and got a reply to PING.\n# TYPE php_redis_session_up gauge\nphp_redis_session_up 1\n"))
+		Expect(recorder.Body.String()).To(ContainSubstring("\nphp_redis_session_keys 2\n"))
+		Expect(recorder.Body.String()).To(ContainSubstring("\nphp_redis_session_keys_truncated 0\n"))
+		Expect(recorder.Body.String()).To(ContainSubstring("\nphp_redis_session_ping_latency_seconds 0.001\n"))
+		Expect(recorder.Body.String()).To(ContainSubstring("\nphp_redis_session_last_sample_timestamp_seconds 1700000000\n"))
+	})
+
+	context("when redis cannot be reached", func() {
+		it.Before(func() {
+			Expect(server.Close()).To(Succeed())
+		})
+
+		it("reports the sample as down", func() {
+			metrics := exporter.Sample()
+			Expect(metrics.Up).To(BeFalse())
+			Expect(metrics.Error).To(ContainSubstring("failed to connect to redis"))
+
+			recorder := httptest.NewRecorder()
+			exporter.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+			Expect(recorder.Body.String()).To(ContainSubstring("\nphp_redis_session_up 0\n"))
+		})
+	})
+}
@@ -0,0 +1,102 @@
+package phpredishandler
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/paketo-buildpacks/packit/v2/chronos"
+)
+
+// sessionIDPattern matches the characters PHP allows in a session ID.
+var sessionIDPattern = regexp.MustCompile(`^[a-zA-Z0-9,-]+$`)
+
+type SkippedSession struct {
+	Path   string
+	Reason string
+}
+
+type SessionMigrationReport struct {
+	Migrated int
+	Skipped  []SkippedSession
+}
+
+type SessionMigrator struct {
+	clock chronos.Clock
+}
+
+func NewSessionMigrator(clock chronos.Clock) SessionMigrator {
+	return SessionMigrator{
+		clock: clock,
+	}
+}
+
+// Migrate copies every PHP session file found under savePath into Redis. Each
+// session is stored under the given key prefix and expires once it would have
+// been garbage collected by the files handler, as determined by the file
+// modification time and maxLifetime.
+func (m SessionMigrator) Migrate(client *RedisClient, prefix, savePath string, maxLifetime time.Duration) (SessionMigrationReport, error) {
+	// The files handler accepts "N;/path" and "N;MODE;/path"; only the
+	// directory matters here as nested directories are walked regardless.
+	if index := strings.LastIndex(savePath, ";"); index >= 0 {
+		savePath = savePath[index+1:]
+	}
+
+	var report SessionMigrationReport
+	err := filepath.WalkDir(savePath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), "sess_") {
+			return nil
+		}
+
+		id := strings.TrimPrefix(entry.Name(), "sess_")
+		if !sessionIDPattern.MatchString(id) {
+			report.Skipped = append(report.Skipped, SkippedSession{Path: path, Reason: "invalid session id"})
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			report.Skipped = append(report.Skipped, SkippedSession{Path: path, Reason: err.Error()})
+			return nil
+		}
+
+		ttl := maxLifetime - m.clock.Now().Sub(info.ModTime())
+		if ttl <= 0 {
+			report.Skipped = append(report.Skipped, SkippedSession{Path: path, Reason: "expired"})
+			return nil
+		}
+
+		if info.Size() == 0 {
+			report.Skipped = append(report.Skipped, SkippedSession{Path: path, Reason: "empty"})
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			report.Skipped = append(report.Skipped, SkippedSession{Path: path, Reason: err.Error()})
+			return nil
+		}
+
+		err = client.Set(prefix+id, data, ttl)
+		if err != nil {
+			return fmt.Errorf("failed to store session %s: %w", id, err)
+		}
+
+		report.Migrated++
+
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("failed to migrate sessions from %s: %w", savePath, err)
+	}
+
+	return report, nil
+}
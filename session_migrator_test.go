@@ -0,0 +1,117 @@
+package phpredishandler_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/paketo-buildpacks/packit/v2/chronos"
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/paketo-buildpacks/php-redis-session-handler/fakes"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSessionMigrator(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		savePath string
+		now      time.Time
+		server   *fakes.RedisServer
+		client   *phpredishandler.RedisClient
+
+		migrator phpredishandler.SessionMigrator
+	)
+
+	it.Before(func() {
+		var err error
+		savePath, err = os.MkdirTemp("", "sessions")
+		Expect(err).NotTo(HaveOccurred())
+
+		server, err = fakes.NewRedisServer()
+		Expect(err).NotTo(HaveOccurred())
+
+		client, err = phpredishandler.DialRedis(phpredishandler.RedisConfig{
+			Hostname: server.Hostname(),
+			Port:     server.Port(),
+		}, time.Second)
+		Expect(err).NotTo(HaveOccurred())
+
+		now = time.Now()
+		writeSession := func(name, content string, age time.Duration) {
+			path := filepath.Join(savePath, name)
+			Expect(os.MkdirAll(filepath.Dir(path), os.ModePerm)).To(Succeed())
+			Expect(os.WriteFile(path, []byte(content), 0600)).To(Succeed())
+			Expect(os.Chtimes(path, now.Add(-age), now.Add(-age))).To(Succeed())
+		}
+
+		writeSession("sess_fresh", "user|s:4:\"some\";", 10*time.Minute)
+		writeSession("a/b/sess_nested", "user|s:5:\"other\";", 20*time.Minute)
+		writeSession("sess_expired", "user|s:3:\"old\";", 2*time.Hour)
+		writeSession("sess_empty", "", time.Minute)
+		writeSession("sess_not!valid", "user|", time.Minute)
+		writeSession("not-a-session", "some-content", time.Minute)
+
+		migrator = phpredishandler.NewSessionMigrator(chronos.NewClock(func() time.Time { return now }))
+	})
+
+	it.After(func() {
+		_ = client.Close()
+		Expect(server.Close()).To(Succeed())
+		Expect(os.RemoveAll(savePath)).To(Succeed())
+	})
+
+	it("stores the live sessions in redis with their remaining lifetime", func() {
+		report, err := migrator.Migrate(client, "some-prefix:", savePath, time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(report.Migrated).To(Equal(2))
+		Expect(report.Skipped).To(ConsistOf(
+			phpredishandler.SkippedSession{Path: filepath.Join(savePath, "sess_expired"), Reason: "expired"},
+			phpredishandler.SkippedSession{Path: filepath.Join(savePath, "sess_empty"), Reason: "empty"},
+			phpredishandler.SkippedSession{Path: filepath.Join(savePath, "sess_not!valid"), Reason: "invalid session id"},
+		))
+
+		Expect(server.Keys()).To(Equal([]string{"some-prefix:fresh", "some-prefix:nested"}))
+
+		value, ttl, _ := server.Lookup("some-prefix:fresh")
+		Expect(string(value)).To(Equal("user|s:4:\"some\";"))
+		Expect(ttl).To(BeNumerically("~", 50*time.Minute, time.Second))
+
+		value, ttl, _ = server.Lookup("some-prefix:nested")
+		Expect(string(value)).To(Equal("user|s:5:\"other\";"))
+		Expect(ttl).To(BeNumerically("~", 40*time.Minute, time.Second))
+	})
+
+	context("when the save path uses the depth syntax", func() {
+		it("migrates sessions from the directory", func() {
+			report, err := migrator.Migrate(client, "some-prefix:", "2;0600;"+savePath, time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(report.Migrated).To(Equal(2))
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the save path does not exist", func() {
+			it("returns an error", func() {
+				_, err := migrator.Migrate(client, "some-prefix:", filepath.Join(savePath, "missing"), time.Hour)
+				Expect(err).To(MatchError(ContainSubstring("failed to migrate sessions from")))
+			})
+		})
+
+		context("when redis rejects the session", func() {
+			it.Before(func() {
+				Expect(server.Close()).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := migrator.Migrate(client, "some-prefix:", savePath, time.Hour)
+				Expect(err).To(MatchError(ContainSubstring("failed to store session")))
+			})
+		})
+	})
+}
@@ -0,0 +1,59 @@
+package phpredishandler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The scopes of the session configuration, selected with SessionScopeEnv.
+const (
+	SessionScopeAll = "all"
+	SessionScopeWeb = "web"
+)
+
+// DefaultWebProcesses are the process types that load the session
+// configuration in the web scope when WebProcessesEnv is not set.
+var DefaultWebProcesses = []string{"web"}
+
+// SessionScope describes which processes load the session configuration.
+// The extension= lines are always loaded by every process, so that CLI
+// processes can still use the extensions.
+type SessionScope struct {
+	// Processes are the process types that load the session directives. When
+	// it is empty, every process loads them.
+	Processes []string
+}
+
+// LoadSessionScope returns the scope selected with SessionScopeEnv. In the
+// web scope, the process types are listed in WebProcessesEnv, separated by
+// commas or whitespace.
+func LoadSessionScope(environment Environment) (SessionScope, error) {
+	scope, _ := environment.Lookup(SessionScopeEnv)
+	switch scope {
+	case "", SessionScopeAll:
+		return SessionScope{}, nil
+	case SessionScopeWeb:
+		value, _ := environment.Lookup(WebProcessesEnv)
+		processes := splitList(value)
+		if len(processes) == 0 {
+			processes = DefaultWebProcesses
+		}
+
+		return SessionScope{Processes: processes}, nil
+	}
+
+	return SessionScope{}, fmt.Errorf("invalid %s %q: must be %q or %q", SessionScopeEnv, scope, SessionScopeAll, SessionScopeWeb)
+}
+
+// WebOnly reports whether only some process types load the session
+// directives.
+func (s SessionScope) WebOnly() bool {
+	return len(s.Processes) > 0
+}
+
+// splitList splits a list of values separated by commas or whitespace.
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+}
@@ -0,0 +1,49 @@
+package phpredishandler_test
+
+import (
+	"testing"
+
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSessionScope(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	context("LoadSessionScope", func() {
+		it("loads the session directives in every process by default", func() {
+			scope, err := phpredishandler.LoadSessionScope(phpredishandler.Environment{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(scope.WebOnly()).To(BeFalse())
+
+			scope, err = phpredishandler.LoadSessionScope(phpredishandler.Environment{"BP_PHP_REDIS_SESSION_SCOPE": "all"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(scope.WebOnly()).To(BeFalse())
+		})
+
+		it("loads the session directives in the web process in the web scope", func() {
+			scope, err := phpredishandler.LoadSessionScope(phpredishandler.Environment{"BP_PHP_REDIS_SESSION_SCOPE": "web"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(scope.WebOnly()).To(BeTrue())
+			Expect(scope.Processes).To(Equal([]string{"web"}))
+		})
+
+		it("loads the session directives in the listed process types", func() {
+			scope, err := phpredishandler.LoadSessionScope(phpredishandler.Environment{
+				"BP_PHP_REDIS_SESSION_SCOPE":         "web",
+				"BP_PHP_REDIS_SESSION_WEB_PROCESSES": "web,api fpm",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(scope.Processes).To(Equal([]string{"web", "api", "fpm"}))
+		})
+
+		context("when the scope is unknown", func() {
+			it("returns an error", func() {
+				_, err := phpredishandler.LoadSessionScope(phpredishandler.Environment{"BP_PHP_REDIS_SESSION_SCOPE": "cli"})
+				Expect(err).To(MatchError(`invalid BP_PHP_REDIS_SESSION_SCOPE "cli": must be "all" or "web"`))
+			})
+		})
+	})
+}
@@ -0,0 +1,446 @@
+package phpredishandler
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// sessionSetting is a RedisConfig setting that can be read from a binding
+// entry of the same name, or from a build environment variable that takes
+// precedence over the binding.
+type sessionSetting struct {
+	Name  string
+	Env   string
+	apply func(config *RedisConfig, value string) error
+}
+
+// sessionSettings are the settings shared by the binding and the build
+// environment. The environment variables can also be set in the
+// [build.env] table of a project.toml.
+var sessionSettings = []sessionSetting{
+	{
+		Name: "client",
+		Env:  SessionClientEnv,
+		apply: func(config *RedisConfig, value string) error {
+			_, err := LookupSessionClient(value)
+			if err != nil {
+				return err
+			}
+
+			config.Client = value
+			return nil
+		},
+	},
+	{
+		Name: "locking_enabled",
+		Env:  "BP_PHP_REDIS_SESSION_LOCKING_ENABLED",
+		apply: func(config *RedisConfig, value string) error {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("must be a boolean")
+			}
+
+			config.LockingEnabled = enabled
+			return nil
+		},
+	},
+	{
+		Name: "lock_expire",
+		Env:  "BP_PHP_REDIS_SESSION_LOCK_EXPIRE",
+		apply: func(config *RedisConfig, value string) (err error) {
+			config.LockExpire, err = parseBoundedInt(value, 0, "must be a non-negative number of seconds")
+			return err
+		},
+	},
+	{
+		Name: "lock_wait_time",
+		Env:  "BP_PHP_REDIS_SESSION_LOCK_WAIT_TIME",
+		apply: func(config *RedisConfig, value string) (err error) {
+			config.LockWaitTime, err = parseBoundedInt(value, 1, "must be a positive number of microseconds")
+			return err
+		},
+	},
+	{
+		Name: "lock_retries",
+		Env:  "BP_PHP_REDIS_SESSION_LOCK_RETRIES",
+		apply: func(config *RedisConfig, value string) error {
+			retries, err := strconv.Atoi(value)
+			if err != nil || retries == 0 || retries < -1 {
+				return fmt.Errorf("must be -1 or a positive number of retries")
+			}
+
+			config.LockRetries = retries
+			return nil
+		},
+	},
+	{
+		Name: "gc_maxlifetime",
+		Env:  "BP_PHP_REDIS_SESSION_GC_MAXLIFETIME",
+		apply: func(config *RedisConfig, value string) (err error) {
+			config.GcMaxLifetime, err = parseBoundedInt(value, 1, "must be a positive number of seconds")
+			return err
+		},
+	},
+	{
+		Name: "lazy_write",
+		Env:  "BP_PHP_REDIS_SESSION_LAZY_WRITE",
+		apply: func(config *RedisConfig, value string) (err error) {
+			config.LazyWrite, err = parseOptionalBool(value)
+			return err
+		},
+	},
+	{
+		Name: "early_refresh",
+		Env:  "BP_PHP_REDIS_SESSION_EARLY_REFRESH",
+		apply: func(config *RedisConfig, value string) error {
+			earlyRefresh, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("must be a boolean")
+			}
+
+			config.EarlyRefresh = earlyRefresh
+			return nil
+		},
+	},
+	{
+		Name: "serializer",
+		Env:  "BP_PHP_REDIS_SESSION_SERIALIZER",
+		apply: func(config *RedisConfig, value string) error {
+			if _, ok := SerializerExtensions[value]; !ok {
+				return fmt.Errorf("must be one of %q, %q or %q", "php", "php_serialize", "igbinary")
+			}
+
+			config.Serializer = value
+			return nil
+		},
+	},
+	{
+		Name: "compression",
+		Env:  "BP_PHP_REDIS_SESSION_COMPRESSION",
+		apply: func(config *RedisConfig, value string) error {
+			if _, ok := CompressionLevels[value]; !ok {
+				return fmt.Errorf("must be one of %q, %q or %q", "lzf", "zstd", "lz4")
+			}
+
+			config.Compression = value
+			return nil
+		},
+	},
+	{
+		Name: "compression_level",
+		Env:  "BP_PHP_REDIS_SESSION_COMPRESSION_LEVEL",
+		apply: func(config *RedisConfig, value string) (err error) {
+			config.CompressionLevel, err = parseBoundedInt(value, 1, "must be a positive integer")
+			return err
+		},
+	},
+	{
+		Name: "session_name",
+		Env:  "BP_PHP_REDIS_SESSION_NAME",
+		apply: func(config *RedisConfig, value string) error {
+			if !sessionNamePattern.MatchString(value) {
+				return fmt.Errorf("must be alphanumeric and contain at least one letter")
+			}
+
+			config.SessionName = value
+			return nil
+		},
+	},
+	{
+		Name: "cookie_domain",
+		Env:  "BP_PHP_REDIS_SESSION_COOKIE_DOMAIN",
+		apply: func(config *RedisConfig, value string) error {
+			if strings.ContainsAny(value, " \t\r\n;,\"/") {
+				return fmt.Errorf("must be a domain name")
+			}
+
+			config.CookieDomain = value
+			return nil
+		},
+	},
+	{
+		Name: "cookie_path",
+		Env:  "BP_PHP_REDIS_SESSION_COOKIE_PATH",
+		apply: func(config *RedisConfig, value string) error {
+			if !strings.HasPrefix(value, "/") || strings.ContainsAny(value, " \t\r\n;,\"") {
+				return fmt.Errorf("must be a path starting with /")
+			}
+
+			config.CookiePath = value
+			return nil
+		},
+	},
+	{
+		Name: "cookie_secure",
+		Env:  "BP_PHP_REDIS_SESSION_COOKIE_SECURE",
+		apply: func(config *RedisConfig, value string) (err error) {
+			config.CookieSecure, err = parseOptionalBool(value)
+			return err
+		},
+	},
+	{
+		Name: "cookie_httponly",
+		Env:  "BP_PHP_REDIS_SESSION_COOKIE_HTTPONLY",
+		apply: func(config *RedisConfig, value string) (err error) {
+			config.CookieHttpOnly, err = parseOptionalBool(value)
+			return err
+		},
+	},
+	{
+		Name: "cookie_samesite",
+		Env:  "BP_PHP_REDIS_SESSION_COOKIE_SAMESITE",
+		apply: func(config *RedisConfig, value string) error {
+			for _, sameSite := range []string{"Strict", "Lax", "None"} {
+				if strings.EqualFold(value, sameSite) {
+					config.CookieSameSite = sameSite
+					return nil
+				}
+			}
+
+			return fmt.Errorf("must be one of %q, %q or %q", "Strict", "Lax", "None")
+		},
+	},
+	{
+		Name: "use_strict_mode",
+		Env:  "BP_PHP_REDIS_SESSION_USE_STRICT_MODE",
+		apply: func(config *RedisConfig, value string) (err error) {
+			config.UseStrictMode, err = parseOptionalBool(value)
+			return err
+		},
+	},
+	{
+		Name: "sid_length",
+		Env:  "BP_PHP_REDIS_SESSION_SID_LENGTH",
+		apply: func(config *RedisConfig, value string) error {
+			length, err := strconv.Atoi(value)
+			if err != nil || length < 22 || length > 256 {
+				return fmt.Errorf("must be between 22 and 256")
+			}
+
+			config.SidLength = length
+			return nil
+		},
+	},
+	{
+		Name: "sid_bits_per_character",
+		Env:  "BP_PHP_REDIS_SESSION_SID_BITS_PER_CHARACTER",
+		apply: func(config *RedisConfig, value string) error {
+			bits, err := strconv.Atoi(value)
+			if err != nil || bits < 4 || bits > 6 {
+				return fmt.Errorf("must be 4, 5 or 6")
+			}
+
+			config.SidBitsPerCharacter = bits
+			return nil
+		},
+	},
+	{
+		Name: "tls",
+		Env:  "BP_PHP_REDIS_SESSION_TLS",
+		apply: func(config *RedisConfig, value string) error {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("must be a boolean")
+			}
+
+			config.TLS = enabled
+			return nil
+		},
+	},
+	{
+		Name: "tls_verify_peer",
+		Env:  "BP_PHP_REDIS_SESSION_TLS_VERIFY_PEER",
+		apply: func(config *RedisConfig, value string) (err error) {
+			config.TLSVerifyPeer, err = parseOptionalBool(value)
+			return err
+		},
+	},
+	{
+		Name: "tls_ca_file",
+		Env:  "BP_PHP_REDIS_SESSION_TLS_CA_FILE",
+		apply: func(config *RedisConfig, value string) error {
+			if !filepath.IsAbs(value) || strings.ContainsAny(value, "\"\r\n") {
+				return fmt.Errorf("must be an absolute path")
+			}
+
+			config.TLSCAFile = value
+			return nil
+		},
+	},
+	{
+		Name: "database",
+		Env:  "BP_PHP_REDIS_SESSION_DATABASE",
+		apply: func(config *RedisConfig, value string) (err error) {
+			config.Database, err = parseBoundedInt(value, 0, "must be a non-negative database number")
+			return err
+		},
+	},
+	{
+		Name: "hardened",
+		Env:  "BP_PHP_REDIS_SESSION_HARDENED",
+		apply: func(config *RedisConfig, value string) error {
+			hardened, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("must be a boolean")
+			}
+
+			config.Hardened = hardened
+			return nil
+		},
+	},
+}
+
+// sessionNamePattern matches the session names PHP accepts without a warning.
+var sessionNamePattern = regexp.MustCompile(`^[A-Za-z0-9]*[A-Za-z][A-Za-z0-9]*$`)
+
+// SerializerExtensions maps each supported session.serialize_handler to the
+// PHP extension that provides it, if any.
+var SerializerExtensions = map[string]string{
+	"php":           "",
+	"php_serialize": "",
+	"igbinary":      "igbinary",
+}
+
+// CompressionLevels maps each supported phpredis session compression to its
+// highest compression level. lzf has no levels.
+var CompressionLevels = map[string]int{
+	"lzf":  0,
+	"zstd": 22,
+	"lz4":  12,
+}
+
+// parseOptionalBool parses a boolean setting that keeps the PHP default
+// while it is nil.
+func parseOptionalBool(value string) (*bool, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("must be a boolean")
+	}
+
+	return &b, nil
+}
+
+// parseBoundedInt parses an integer that must not be less than min.
+func parseBoundedInt(value string, min int, message string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min {
+		return 0, fmt.Errorf("%s", message)
+	}
+
+	return n, nil
+}
+
+// ApplyEnvironment returns a copy of the config with the settings that are
+// set in the build environment, which take precedence over the binding.
+func (c RedisConfig) ApplyEnvironment(environment Environment) (RedisConfig, error) {
+	overrides := map[string]string{}
+	for _, setting := range sessionSettings {
+		value, ok := environment.Lookup(setting.Env)
+		if !ok || value == "" {
+			continue
+		}
+
+		err := setting.apply(&c, value)
+		if err != nil {
+			return RedisConfig{}, fmt.Errorf("invalid %s %q in %s: %w", setting.Name, value, setting.Env, err)
+		}
+		overrides[setting.Name] = setting.Env
+	}
+
+	if len(overrides) > 0 {
+		sources := map[string]string{}
+		for name, source := range c.Sources {
+			sources[name] = source
+		}
+		for name, source := range overrides {
+			sources[name] = source
+		}
+		c.Sources = sources
+	}
+
+	err := validateSessionSettings(c)
+	if err != nil {
+		return RedisConfig{}, err
+	}
+
+	return c, nil
+}
+
+// validateSessionSettings rejects settings that are only invalid in
+// combination, as they can come from both the binding and the environment.
+func validateSessionSettings(config RedisConfig) error {
+	if !config.TLS {
+		for _, name := range []string{"tls_verify_peer", "tls_ca_file"} {
+			if source := config.Sources[name]; source != "" && source != SourceDefault {
+				return fmt.Errorf("invalid %s in %s: requires tls", name, source)
+			}
+		}
+	}
+
+	if config.CompressionLevel == 0 {
+		return nil
+	}
+
+	if config.Compression == "" {
+		return fmt.Errorf("invalid compression_level %d in %s: requires a compression", config.CompressionLevel, config.Sources["compression_level"])
+	}
+
+	maxLevel := CompressionLevels[config.Compression]
+	if config.CompressionLevel > maxLevel {
+		if maxLevel == 0 {
+			return fmt.Errorf("invalid compression_level %d in %s: %s compression has no levels", config.CompressionLevel, config.Sources["compression_level"], config.Compression)
+		}
+
+		return fmt.Errorf("invalid compression_level %d in %s: %s compression levels are between 1 and %d", config.CompressionLevel, config.Sources["compression_level"], config.Compression, maxLevel)
+	}
+
+	return nil
+}
+
+// requiresPhpRedis reports whether the config uses settings that only the
+// phpredis session client supports.
+func (c RedisConfig) requiresPhpRedis() bool {
+	return c.LockingEnabled || c.EarlyRefresh || c.Compression != ""
+}
+
+// Extensions returns the PHP extensions the client needs for this config:
+// the extensions of the client and the extension of the serializer, which is
+// only loaded when it is used.
+func (c RedisConfig) Extensions(client SessionClient) []string {
+	extensions := append([]string{}, client.Extensions...)
+
+	serializerExtension := SerializerExtensions[c.Serializer]
+	if serializerExtension == "" || slices.Contains(extensions, serializerExtension) {
+		return extensions
+	}
+
+	return append(extensions, serializerExtension)
+}
+
+// Warnings describes combinations of settings that are valid but unlikely to
+// behave as intended.
+func (c RedisConfig) Warnings() []string {
+	var warnings []string
+	if c.LockingEnabled && c.GcMaxLifetime > 0 && c.LockExpire > c.GcMaxLifetime {
+		warnings = append(warnings, fmt.Sprintf("gc_maxlifetime of %ds is shorter than the lock_expire of %ds: sessions can expire while they are locked", c.GcMaxLifetime, c.LockExpire))
+	}
+
+	if !c.LockingEnabled && (c.LockExpire != 0 || c.LockWaitTime != 0 || c.LockRetries != 0) {
+		warnings = append(warnings, "lock_expire, lock_wait_time and lock_retries have no effect unless locking_enabled is true")
+	}
+
+	if c.EarlyRefresh && c.LazyWrite != nil && !*c.LazyWrite {
+		warnings = append(warnings, "early_refresh has no effect when lazy_write is false: every request writes the session and refreshes its TTL")
+	}
+
+	hardened := c.WithHardening()
+	if hardened.CookieSameSite == "None" && (hardened.CookieSecure == nil || !*hardened.CookieSecure) {
+		warnings = append(warnings, "cookie_samesite None requires cookie_secure: browsers reject the session cookie otherwise")
+	}
+
+	return warnings
+}
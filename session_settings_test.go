@@ -0,0 +1,173 @@
+package phpredishandler_test
+
+import (
+	"testing"
+
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSessionSettings(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		config phpredishandler.RedisConfig
+	)
+
+	it.Before(func() {
+		config = phpredishandler.RedisConfig{
+			Hostname:     "some-host",
+			Port:         6379,
+			LockWaitTime: 50000,
+			Sources: map[string]string{
+				"lock_wait_time": "some-binding-path/lock_wait_time",
+			},
+		}
+	})
+
+	context("ApplyEnvironment", func() {
+		it("leaves the config unchanged when nothing is set", func() {
+			Expect(config.ApplyEnvironment(phpredishandler.Environment{})).To(Equal(config))
+		})
+
+		it("overrides the binding with the build environment", func() {
+			applied, err := config.ApplyEnvironment(phpredishandler.Environment{
+				"BP_PHP_REDIS_SESSION_CLIENT":          "relay",
+				"BP_PHP_REDIS_SESSION_LOCKING_ENABLED": "true",
+				"BP_PHP_REDIS_SESSION_LOCK_EXPIRE":     "10",
+				"BP_PHP_REDIS_SESSION_LOCK_WAIT_TIME":  "2000",
+				"BP_PHP_REDIS_SESSION_LOCK_RETRIES":    "5",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(applied.Client).To(Equal("relay"))
+			Expect(applied.LockingEnabled).To(BeTrue())
+			Expect(applied.LockExpire).To(Equal(10))
+			Expect(applied.LockWaitTime).To(Equal(2000))
+			Expect(applied.LockRetries).To(Equal(5))
+			Expect(applied.Sources).To(Equal(map[string]string{
+				"client":          "BP_PHP_REDIS_SESSION_CLIENT",
+				"locking_enabled": "BP_PHP_REDIS_SESSION_LOCKING_ENABLED",
+				"lock_expire":     "BP_PHP_REDIS_SESSION_LOCK_EXPIRE",
+				"lock_wait_time":  "BP_PHP_REDIS_SESSION_LOCK_WAIT_TIME",
+				"lock_retries":    "BP_PHP_REDIS_SESSION_LOCK_RETRIES",
+			}))
+
+			Expect(config.Sources).To(HaveKeyWithValue("lock_wait_time", "some-binding-path/lock_wait_time"))
+		})
+
+		it("overrides the lifetime settings", func() {
+			applied, err := config.ApplyEnvironment(phpredishandler.Environment{
+				"BP_PHP_REDIS_SESSION_GC_MAXLIFETIME": "7200",
+				"BP_PHP_REDIS_SESSION_LAZY_WRITE":     "true",
+				"BP_PHP_REDIS_SESSION_EARLY_REFRESH":  "true",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(applied.GcMaxLifetime).To(Equal(7200))
+			Expect(applied.LazyWrite).NotTo(BeNil())
+			Expect(*applied.LazyWrite).To(BeTrue())
+			Expect(applied.EarlyRefresh).To(BeTrue())
+			Expect(applied.Sources).To(HaveKeyWithValue("lazy_write", "BP_PHP_REDIS_SESSION_LAZY_WRITE"))
+		})
+
+		context("failure cases", func() {
+			it("validates the combined settings", func() {
+				config.Compression = "zstd"
+				config.CompressionLevel = 9
+				config.Sources["compression_level"] = "some-binding-path/compression_level"
+
+				_, err := config.ApplyEnvironment(phpredishandler.Environment{
+					"BP_PHP_REDIS_SESSION_COMPRESSION": "lzf",
+				})
+				Expect(err).To(MatchError("invalid compression_level 9 in some-binding-path/compression_level: lzf compression has no levels"))
+			})
+
+			it("returns an error naming the environment variable", func() {
+				_, err := config.ApplyEnvironment(phpredishandler.Environment{
+					"BP_PHP_REDIS_SESSION_LOCK_EXPIRE": "-1",
+				})
+				Expect(err).To(MatchError(`invalid lock_expire "-1" in BP_PHP_REDIS_SESSION_LOCK_EXPIRE: must be a non-negative number of seconds`))
+			})
+
+			it("rejects an unknown client", func() {
+				_, err := config.ApplyEnvironment(phpredishandler.Environment{
+					"BP_PHP_REDIS_SESSION_CLIENT": "some-client",
+				})
+				Expect(err).To(MatchError(ContainSubstring(`unknown session client "some-client"`)))
+			})
+		})
+	})
+
+	context("Extensions", func() {
+		it("returns the client extensions", func() {
+			client, err := phpredishandler.LookupSessionClient("phpredis")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(config.Extensions(client)).To(Equal([]string{"redis"}))
+		})
+
+		it("adds the extension of the serializer", func() {
+			client, err := phpredishandler.LookupSessionClient("phpredis")
+			Expect(err).NotTo(HaveOccurred())
+
+			config.Serializer = "igbinary"
+			Expect(config.Extensions(client)).To(Equal([]string{"redis", "igbinary"}))
+		})
+
+		it("does not add an extension the client already needs", func() {
+			client, err := phpredishandler.LookupSessionClient("relay")
+			Expect(err).NotTo(HaveOccurred())
+
+			config.Serializer = "igbinary"
+			Expect(config.Extensions(client)).To(Equal([]string{"relay", "igbinary", "msgpack"}))
+		})
+	})
+
+	context("Warnings", func() {
+		it("returns nothing for a coherent config", func() {
+			config.LockingEnabled = true
+			config.LockExpire = 30
+			config.GcMaxLifetime = 1440
+			Expect(config.Warnings()).To(BeEmpty())
+		})
+
+		it("warns when the lifetime is shorter than lock_expire", func() {
+			config.LockingEnabled = true
+			config.LockExpire = 600
+			config.GcMaxLifetime = 300
+			Expect(config.Warnings()).To(Equal([]string{
+				"gc_maxlifetime of 300s is shorter than the lock_expire of 600s: sessions can expire while they are locked",
+			}))
+		})
+
+		it("warns when lock settings are set without locking", func() {
+			Expect(config.Warnings()).To(Equal([]string{
+				"lock_expire, lock_wait_time and lock_retries have no effect unless locking_enabled is true",
+			}))
+		})
+
+		it("warns when cookie_samesite is None without cookie_secure", func() {
+			config.LockWaitTime = 0
+			config.CookieSameSite = "None"
+			Expect(config.Warnings()).To(Equal([]string{
+				"cookie_samesite None requires cookie_secure: browsers reject the session cookie otherwise",
+			}))
+
+			config.Hardened = true
+			Expect(config.Warnings()).To(BeEmpty())
+		})
+
+		it("warns when early_refresh is set without lazy_write", func() {
+			lazyWrite := false
+			config.LockWaitTime = 0
+			config.LazyWrite = &lazyWrite
+			config.EarlyRefresh = true
+			Expect(config.Warnings()).To(Equal([]string{
+				"early_refresh has no effect when lazy_write is false: every request writes the session and refreshes its TTL",
+			}))
+		})
+	})
+}
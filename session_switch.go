@@ -0,0 +1,136 @@
+package phpredishandler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// disabledIni overrides the session handler with PHP's default files handler.
+// The extensions stay loaded, so that application code using them directly
+// keeps working.
+const disabledIni = `; Written by the php-redis-session-handler buildpack. Loaded last when
+; BPL_PHP_REDIS_SESSION_ENABLED is false.
+[session]
+session.save_handler = files
+session.save_path = ""
+`
+
+// WriteDisabledIni writes the ini file that the session-switch exec.d helper
+// loads after all others when sessions are disabled at launch. When
+// clearPrependFile is set, the auto_prepend_file that registers the handler of
+// a pure PHP session client is cleared as well.
+func WriteDisabledIni(layerPath string, clearPrependFile bool) (string, error) {
+	dir := filepath.Join(layerPath, DisabledIniDir)
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+
+	content := disabledIni
+	if clearPrependFile {
+		content = strings.Replace(content, "[session]\n", "auto_prepend_file = \"\"\n\n[session]\n", 1)
+	}
+
+	path := filepath.Join(dir, "php-redis-disabled.ini")
+	err = os.WriteFile(path, []byte(content), os.ModePerm)
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// SessionSwitch returns the environment variables the session-switch exec.d
+// helper sets at launch. When IniLoadLastEnv is true, the directories of the
+// layer are moved to the end of PHP_INI_SCAN_DIR, in their original order, so
+// that they are loaded after those of later buildpacks. When SessionEnabledEnv
+// is false, the DisabledIniDir of the layer is appended after them so that its
+// directives take precedence over the session configuration. Otherwise nothing
+// is changed.
+func SessionSwitch(environment Environment, layerPath string) (Environment, error) {
+	disabled, err := SessionsDisabled(environment)
+	if err != nil {
+		return nil, err
+	}
+
+	loadLast, err := environment.Bool(IniLoadLastEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	if !disabled && !loadLast {
+		return nil, nil
+	}
+
+	var dirs []string
+	scanDir, _ := environment.Lookup("PHP_INI_SCAN_DIR")
+	if scanDir != "" {
+		dirs = filepath.SplitList(scanDir)
+	}
+
+	if loadLast {
+		var others, ours []string
+		for _, dir := range dirs {
+			if dir == layerPath || strings.HasPrefix(dir, layerPath+string(filepath.Separator)) {
+				ours = append(ours, dir)
+				continue
+			}
+			others = append(others, dir)
+		}
+		dirs = append(others, ours...)
+	}
+
+	if disabled {
+		dirs = append(dirs, filepath.Join(layerPath, DisabledIniDir))
+	}
+
+	value := strings.Join(dirs, string(os.PathListSeparator))
+	if value == scanDir {
+		return nil, nil
+	}
+
+	return Environment{
+		"PHP_INI_SCAN_DIR": value,
+	}, nil
+}
+
+// SessionSwitchWarnings returns why sessions keep using the configured backend
+// when SessionEnabledEnv disables the session configuration: a php-fpm pool
+// that locks the session handler with php_admin_value, which ini files cannot
+// override, and a Laravel SESSION_DRIVER of redis, which Laravel uses instead
+// of the session handler.
+func SessionSwitchWarnings(environment Environment, layerPath string) []string {
+	var warnings []string
+
+	poolPath := filepath.Join(layerPath, FpmPoolFile)
+	_, err := os.Stat(poolPath)
+	if err == nil {
+		warnings = append(warnings, fmt.Sprintf("the php-fpm pool configuration %s locks the session handler, which the files handler cannot override: rebuild without %s", poolPath, FpmAdminEnv))
+	}
+
+	driver, _ := environment.Lookup("SESSION_DRIVER")
+	if driver == "redis" {
+		warnings = append(warnings, "SESSION_DRIVER is redis, so Laravel keeps storing sessions in Redis: set SESSION_DRIVER=file")
+	}
+
+	return warnings
+}
+
+// SessionsDisabled reports whether SessionEnabledEnv disables the session
+// configuration.
+func SessionsDisabled(environment Environment) (bool, error) {
+	value, _ := environment.Lookup(SessionEnabledEnv)
+	if value == "" {
+		return false, nil
+	}
+
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %s: %q is not a boolean", SessionEnabledEnv, value)
+	}
+
+	return !enabled, nil
+}
@@ -0,0 +1,179 @@
+package phpredishandler_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	phpredishandler "github.com/paketo-buildpacks/php-redis-session-handler"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSessionSwitch(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		layerPath string
+	)
+
+	it.Before(func() {
+		layerPath = t.TempDir()
+	})
+
+	context("WriteDisabledIni", func() {
+		it("writes an ini file that selects the files handler", func() {
+			path, err := phpredishandler.WriteDisabledIni(layerPath, false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(layerPath, "disabled", "php-redis-disabled.ini")))
+
+			contents, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(ContainSubstring("[session]\nsession.save_handler = files\nsession.save_path = \"\"\n"))
+			Expect(string(contents)).NotTo(ContainSubstring("auto_prepend_file"))
+		})
+
+		it("clears the auto_prepend_file of a pure PHP session client", func() {
+			path, err := phpredishandler.WriteDisabledIni(layerPath, true)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(ContainSubstring("auto_prepend_file = \"\"\n\n[session]\n"))
+		})
+
+		context("when the layer is not writable", func() {
+			it.Before(func() {
+				Expect(os.Chmod(layerPath, 0500)).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Chmod(layerPath, os.ModePerm)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				_, err := phpredishandler.WriteDisabledIni(layerPath, false)
+				Expect(err).To(MatchError(ContainSubstring("permission denied")))
+			})
+		})
+	})
+
+	context("SessionSwitch", func() {
+		it("changes nothing unless sessions are disabled", func() {
+			env, err := phpredishandler.SessionSwitch(phpredishandler.Environment{}, layerPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(env).To(BeEmpty())
+
+			env, err = phpredishandler.SessionSwitch(phpredishandler.Environment{"BPL_PHP_REDIS_SESSION_ENABLED": "true"}, layerPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(env).To(BeEmpty())
+		})
+
+		it("loads the disabled ini after the other ini files", func() {
+			env, err := phpredishandler.SessionSwitch(phpredishandler.Environment{
+				"BPL_PHP_REDIS_SESSION_ENABLED": "false",
+				"PHP_INI_SCAN_DIR":              "/layers/php/etc:" + layerPath,
+			}, layerPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(env).To(Equal(phpredishandler.Environment{
+				"PHP_INI_SCAN_DIR": "/layers/php/etc:" + layerPath + ":" + filepath.Join(layerPath, "disabled"),
+			}))
+		})
+
+		context("when PHP_INI_SCAN_DIR is not set", func() {
+			it("only loads the disabled ini", func() {
+				env, err := phpredishandler.SessionSwitch(phpredishandler.Environment{"BPL_PHP_REDIS_SESSION_ENABLED": "0"}, layerPath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(env).To(Equal(phpredishandler.Environment{
+					"PHP_INI_SCAN_DIR": filepath.Join(layerPath, "disabled"),
+				}))
+			})
+		})
+
+		context("when the value is not a boolean", func() {
+			it("returns an error", func() {
+				_, err := phpredishandler.SessionSwitch(phpredishandler.Environment{"BPL_PHP_REDIS_SESSION_ENABLED": "maybe"}, layerPath)
+				Expect(err).To(MatchError(`failed to parse BPL_PHP_REDIS_SESSION_ENABLED: "maybe" is not a boolean`))
+			})
+		})
+
+		context("when the layer is loaded last", func() {
+			var environment phpredishandler.Environment
+
+			it.Before(func() {
+				environment = phpredishandler.Environment{
+					"BPL_PHP_REDIS_SESSION_INI_LAST": "true",
+					"PHP_INI_SCAN_DIR":               "/layers/php/etc:" + layerPath + ":" + filepath.Join(layerPath, "web") + ":/workspace/.php.ini.d:" + layerPath + "-other",
+				}
+			})
+
+			it("moves the directories of the layer after the other ones", func() {
+				env, err := phpredishandler.SessionSwitch(environment, layerPath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(env).To(Equal(phpredishandler.Environment{
+					"PHP_INI_SCAN_DIR": "/layers/php/etc:/workspace/.php.ini.d:" + layerPath + "-other:" + layerPath + ":" + filepath.Join(layerPath, "web"),
+				}))
+			})
+
+			it("loads the disabled ini after them when sessions are disabled", func() {
+				environment["BPL_PHP_REDIS_SESSION_ENABLED"] = "false"
+
+				env, err := phpredishandler.SessionSwitch(environment, layerPath)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(env).To(Equal(phpredishandler.Environment{
+					"PHP_INI_SCAN_DIR": "/layers/php/etc:/workspace/.php.ini.d:" + layerPath + "-other:" + layerPath + ":" + filepath.Join(layerPath, "web") + ":" + filepath.Join(layerPath, "disabled"),
+				}))
+			})
+
+			context("when the layer is already last", func() {
+				it.Before(func() {
+					environment["PHP_INI_SCAN_DIR"] = "/layers/php/etc:" + layerPath
+				})
+
+				it("changes nothing", func() {
+					env, err := phpredishandler.SessionSwitch(environment, layerPath)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(env).To(BeEmpty())
+				})
+			})
+
+			context("when the value is not a boolean", func() {
+				it.Before(func() {
+					environment["BPL_PHP_REDIS_SESSION_INI_LAST"] = "maybe"
+				})
+
+				it("returns an error", func() {
+					_, err := phpredishandler.SessionSwitch(environment, layerPath)
+					Expect(err).To(MatchError(`failed to parse BPL_PHP_REDIS_SESSION_INI_LAST: "maybe" is not a boolean`))
+				})
+			})
+		})
+	})
+
+	context("SessionSwitchWarnings", func() {
+		it("returns no warnings", func() {
+			Expect(phpredishandler.SessionSwitchWarnings(phpredishandler.Environment{"SESSION_DRIVER": "file"}, layerPath)).To(BeEmpty())
+		})
+
+		context("when the php-fpm pool locks the session handler", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(layerPath, "php-redis-session.conf"), []byte("[www]\n"), os.ModePerm)).To(Succeed())
+			})
+
+			it("warns that the files handler cannot override it", func() {
+				Expect(phpredishandler.SessionSwitchWarnings(phpredishandler.Environment{}, layerPath)).To(Equal([]string{
+					"the php-fpm pool configuration " + filepath.Join(layerPath, "php-redis-session.conf") + " locks the session handler, which the files handler cannot override: rebuild without BP_PHP_REDIS_SESSION_FPM_ADMIN",
+				}))
+			})
+		})
+
+		context("when Laravel uses the redis session driver", func() {
+			it("warns that Laravel keeps using Redis", func() {
+				Expect(phpredishandler.SessionSwitchWarnings(phpredishandler.Environment{"SESSION_DRIVER": "redis"}, layerPath)).To(Equal([]string{
+					"SESSION_DRIVER is redis, so Laravel keeps storing sessions in Redis: set SESSION_DRIVER=file",
+				}))
+			})
+		})
+	})
+}